@@ -13,7 +13,10 @@ import (
 	"github.com/hamedetemaad/lineq-operator/internal/config"
 	"github.com/hamedetemaad/lineq-operator/internal/runner"
 	"github.com/hamedetemaad/lineq-operator/pkg/controller"
+	"github.com/hamedetemaad/lineq-operator/pkg/health"
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq"
 	wrv1alpha1clientset "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1/apis/clientset/versioned"
+	"github.com/hamedetemaad/lineq-operator/pkg/webhook"
 
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -48,10 +51,23 @@ func main() {
 		logger.Fatal("error creating lineq client ", err)
 	}
 
+	lineqClient := lineq.New(
+		lineq.Options{
+			Addr:    config.LineqHttpAddr,
+			Port:    config.LineqHttpPort,
+			Timeout: config.LineqHttpTimeout,
+		},
+		logger.WithField("type", "lineq"),
+	)
+
 	ctrl := controller.New(
 		kubeClientSet,
 		wrv1alpha1ClientSet,
+		lineqClient,
 		config.Namespace,
+		config.WatchNamespaces,
+		config.ShardSelector,
+		config.HAProxy,
 		logger.WithField("type", "controller"),
 	)
 
@@ -77,12 +93,43 @@ func main() {
 		defer m.Stop()
 	}
 
+	if config.Webhook.Enabled {
+		w := webhook.New(
+			kubeClientSet,
+			wrv1alpha1ClientSet,
+			config.WatchNamespaces,
+			webhook.Options{
+				Port:     config.Webhook.Port,
+				CertFile: config.Webhook.CertFile,
+				KeyFile:  config.Webhook.KeyFile,
+			},
+			logger.WithField("type", "webhook"),
+		)
+		go w.Start(ctx)
+		defer w.Stop()
+	}
+
 	r := runner.NewRunner(
 		ctrl,
 		kubeClientSet,
+		lineqClient,
 		config,
 		logger.WithField("type", "runner"),
 	)
+
+	h := health.New(
+		health.Options{
+			Port:    config.Health.Port,
+			Timeout: config.Health.Timeout,
+		},
+		logger.WithField("type", "health"),
+	)
+	for name, check := range r.ReadinessChecks() {
+		h.AddReadinessCheck(name, check)
+	}
+	go h.Start()
+	defer h.Stop()
+
 	r.Start(ctx)
 }
 
@@ -1,11 +1,18 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/gotway/gotway/pkg/env"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 type HA struct {
@@ -44,40 +51,109 @@ func (m Metrics) String() string {
 	)
 }
 
+type Health struct {
+	Port    string
+	Timeout time.Duration
+}
+
+func (h Health) String() string {
+	return fmt.Sprintf(
+		"Health{Port='%s'Timeout='%v'}",
+		h.Port,
+		h.Timeout,
+	)
+}
+
+type Webhook struct {
+	Enabled  bool
+	Port     string
+	CertFile string
+	KeyFile  string
+}
+
+func (w Webhook) String() string {
+	return fmt.Sprintf(
+		"Webhook{Enabled='%v'Port='%s'CertFile='%s'KeyFile='%s'}",
+		w.Enabled,
+		w.Port,
+		w.CertFile,
+		w.KeyFile,
+	)
+}
+
+type HAProxy struct {
+	Namespace             string
+	ConfigMapName         string
+	AuxConfigMapName      string
+	TemplateConfigMapName string
+	// ConfigWriter is set on the single instance writing the HAProxy
+	// configmaps, every other shard or tenant operator leaves them alone.
+	ConfigWriter bool
+}
+
+func (h HAProxy) String() string {
+	return fmt.Sprintf(
+		"HAProxy{Namespace='%s'ConfigMapName='%s'AuxConfigMapName='%s'TemplateConfigMapName='%s'ConfigWriter='%v'}",
+		h.Namespace,
+		h.ConfigMapName,
+		h.AuxConfigMapName,
+		h.TemplateConfigMapName,
+		h.ConfigWriter,
+	)
+}
+
 type Config struct {
-	KubeConfig           string
-	Namespace            string
-	NumWorkers           int
-	HA                   HA
-	Metrics              Metrics
-	Env                  string
-	LogLevel             string
-	LineqTcpAddr         string
-	LineqHttpAddr        string
-	LineqTcpPort         int
-	LineqHttpPort        int
-	RoomTableName        string
-	UserTableName        string
-	LineqSessionDuration int
+	KubeConfig              string
+	Namespace               string
+	WatchNamespaces         []string
+	ShardSelector           string
+	NumWorkers              int
+	HA                      HA
+	Metrics                 Metrics
+	Health                  Health
+	HAProxy                 HAProxy
+	Webhook                 Webhook
+	Env                     string
+	LogLevel                string
+	LineqTcpAddr            string
+	LineqHttpAddr           string
+	LineqTcpPort            int
+	LineqHttpPort           int
+	LineqHttpTimeout        time.Duration
+	LineqConfigTimeout      time.Duration
+	RoomTableName           string
+	UserTableName           string
+	LineqSessionDuration    int
+	StatsSyncInterval       time.Duration
+	LineqConfigSyncInterval time.Duration
 }
 
 func (c Config) String() string {
 	return fmt.Sprintf(
-		"Config{KubeConfig='%s'Namespace='%s'NumWorkers='%d'HA='%v'Metrics='%v'Env='%s'LogLevel='%s'LineqTcpAddr='%s'LineqHttpAddr='%s'LineqTcpPort='%d'LineqHttpPort='%d'RoomTableName='%s'UserTableName='%s'LineqSessionDuration='%d'}",
+		"Config{KubeConfig='%s'Namespace='%s'WatchNamespaces='%v'ShardSelector='%s'NumWorkers='%d'HA='%v'Metrics='%v'Health='%v'HAProxy='%v'Webhook='%v'Env='%s'LogLevel='%s'LineqTcpAddr='%s'LineqHttpAddr='%s'LineqTcpPort='%d'LineqHttpPort='%d'LineqHttpTimeout='%v'LineqConfigTimeout='%v'RoomTableName='%s'UserTableName='%s'LineqSessionDuration='%d'StatsSyncInterval='%v'LineqConfigSyncInterval='%v'}",
 		c.KubeConfig,
 		c.Namespace,
+		c.WatchNamespaces,
+		c.ShardSelector,
 		c.NumWorkers,
 		c.HA,
 		c.Metrics,
+		c.Health,
+		c.HAProxy,
+		c.Webhook,
 		c.Env,
 		c.LogLevel,
 		c.LineqTcpAddr,
 		c.LineqHttpAddr,
 		c.LineqTcpPort,
 		c.LineqHttpPort,
+		c.LineqHttpTimeout,
+		c.LineqConfigTimeout,
 		c.RoomTableName,
 		c.UserTableName,
 		c.LineqSessionDuration,
+		c.StatsSyncInterval,
+		c.LineqConfigSyncInterval,
 	)
 }
 
@@ -96,14 +172,73 @@ func GetConfig() (Config, error) {
 		}
 	}
 
+	var watchNamespace, shardSelector string
+	haproxy := HAProxy{}
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	flags.StringVar(
+		&watchNamespace,
+		"watch-namespace",
+		env.Get("WATCH_NAMESPACE", ""),
+		"comma separated namespaces to watch, empty or '*' watches all namespaces",
+	)
+	flags.StringVar(
+		&shardSelector,
+		"shard-selector",
+		env.Get("SHARD_SELECTOR", ""),
+		"optional label selector limiting the waiting rooms handled by this instance, e.g. lineq.io/shard=a",
+	)
+	flags.StringVar(
+		&haproxy.Namespace,
+		"haproxy-namespace",
+		env.Get("HAPROXY_NAMESPACE", "haproxy-controller"),
+		"namespace of the HAProxy ingress controller",
+	)
+	flags.StringVar(
+		&haproxy.ConfigMapName,
+		"haproxy-configmap",
+		env.Get("HAPROXY_CONFIGMAP", "haproxy-kubernetes-ingress"),
+		"name of the HAProxy ingress controller configmap",
+	)
+	flags.StringVar(
+		&haproxy.AuxConfigMapName,
+		"haproxy-aux-configmap",
+		env.Get("HAPROXY_AUX_CONFIGMAP", "haproxy-auxiliary-configmap"),
+		"name of the configmap holding haproxy-auxiliary.cfg",
+	)
+	flags.StringVar(
+		&haproxy.TemplateConfigMapName,
+		"haproxy-template-configmap",
+		env.Get("HAPROXY_TEMPLATE_CONFIGMAP", ""),
+		"optional configmap overriding the frontend.cfg.tmpl and auxiliary.cfg.tmpl templates",
+	)
+	flags.BoolVar(
+		&haproxy.ConfigWriter,
+		"haproxy-config-writer",
+		env.GetBool("HAPROXY_CONFIG_WRITER", true),
+		"write the HAProxy configmaps, set on exactly one instance per HAProxy",
+	)
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		return Config{}, fmt.Errorf("error parsing flags %v", err)
+	}
+
+	if shardSelector != "" {
+		selector, err := labels.Parse(shardSelector)
+		if err != nil {
+			return Config{}, fmt.Errorf("error parsing shard selector %v", err)
+		}
+		shardSelector = selector.String()
+	}
+
 	return Config{
-		KubeConfig: env.Get("KUBECONFIG", ""),
-		Namespace:  env.Get("NAMESPACE", "default"),
-		NumWorkers: env.GetInt("NUM_WORKERS", 4),
+		KubeConfig:      env.Get("KUBECONFIG", ""),
+		Namespace:       env.Get("NAMESPACE", "default"),
+		WatchNamespaces: ParseNamespaces(watchNamespace),
+		ShardSelector:   shardSelector,
+		NumWorkers:      env.GetInt("NUM_WORKERS", 4),
 		HA: HA{
 			Enabled:       ha,
 			NodeId:        nodeId,
-			LeaseLockName: env.Get("HA_LEASE_LOCK_NAME", "waitingroomoperator"),
+			LeaseLockName: ShardLeaseName(env.Get("HA_LEASE_LOCK_NAME", "waitingroomoperator"), shardSelector),
 			LeaseDuration: env.GetDuration("HA_LEASE_DURATION_SECONDS", 15) * time.Second,
 			RenewDeadline: env.GetDuration("HA_RENEW_DEADLINE_SECONDS", 10) * time.Second,
 			RetryPeriod:   env.GetDuration("HA_RETRY_PERIOD_SECONDS", 2) * time.Second,
@@ -113,11 +248,71 @@ func GetConfig() (Config, error) {
 			Path:    env.Get("METRICS_PATH", "/metrics"),
 			Port:    env.Get("METRICS_PORT", "2112"),
 		},
-		Env:           env.Get("ENV", "local"),
-		LogLevel:      env.Get("LOG_LEVEL", "debug"),
-		LineqTcpAddr:  env.Get("LINEQ_TCP_ADDR", "lineq-tcp.lineq.svc"),
-		LineqHttpAddr: env.Get("LINEQ_HTTP_ADDR", "lineq-http.lineq.svc"),
-		LineqTcpPort:  env.GetInt("LINEQ_TCP_PORT", 11111),
-		LineqHttpPort: env.GetInt("LINEQ_HTTP_PORT", 8060),
+		Health: Health{
+			Port:    env.Get("HEALTH_PORT", "8081"),
+			Timeout: env.GetDuration("HEALTH_TIMEOUT_SECONDS", 5) * time.Second,
+		},
+		HAProxy: haproxy,
+		Webhook: Webhook{
+			Enabled:  env.GetBool("WEBHOOK_ENABLED", false),
+			Port:     env.Get("WEBHOOK_PORT", "9443"),
+			CertFile: env.Get("WEBHOOK_CERT_FILE", "/etc/webhook/certs/tls.crt"),
+			KeyFile:  env.Get("WEBHOOK_KEY_FILE", "/etc/webhook/certs/tls.key"),
+		},
+		Env:                     env.Get("ENV", "local"),
+		LogLevel:                env.Get("LOG_LEVEL", "debug"),
+		LineqTcpAddr:            env.Get("LINEQ_TCP_ADDR", "lineq-tcp.lineq.svc"),
+		LineqHttpAddr:           env.Get("LINEQ_HTTP_ADDR", "lineq-http.lineq.svc"),
+		LineqTcpPort:            env.GetInt("LINEQ_TCP_PORT", 11111),
+		LineqHttpPort:           env.GetInt("LINEQ_HTTP_PORT", 8060),
+		LineqHttpTimeout:        env.GetDuration("LINEQ_HTTP_TIMEOUT_SECONDS", 5) * time.Second,
+		LineqConfigTimeout:      env.GetDuration("LINEQ_CONFIG_TIMEOUT_SECONDS", 60) * time.Second,
+		StatsSyncInterval:       env.GetDuration("STATS_SYNC_INTERVAL_SECONDS", 10) * time.Second,
+		LineqConfigSyncInterval: env.GetDuration("LINEQ_CONFIG_SYNC_INTERVAL_SECONDS", 60) * time.Second,
 	}, nil
 }
+
+// ParseNamespaces splits a comma separated namespace list. An empty list or
+// '*' returns nil, meaning all namespaces.
+func ParseNamespaces(value string) []string {
+	var namespaces []string
+	seen := make(map[string]bool)
+	for _, ns := range strings.Split(value, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns == "*" {
+			return nil
+		}
+		if ns == "" || seen[ns] {
+			continue
+		}
+		seen[ns] = true
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces
+}
+
+var invalidLeaseNameChars = regexp.MustCompile(`[^a-z0-9.]+`)
+
+// ShardLeaseName suffixes the lease name with the shard selector, so every
+// shard elects its own leader. The readable part of the suffix drops the
+// operators and the case of the selector, a hash of the parsed selector keeps
+// selectors like 'shard=a' and 'shard!=a' on different leases.
+func ShardLeaseName(name, shardSelector string) string {
+	if strings.TrimSpace(shardSelector) == "" {
+		return name
+	}
+	if selector, err := labels.Parse(shardSelector); err == nil {
+		shardSelector = selector.String()
+	}
+	sum := sha256.Sum256([]byte(shardSelector))
+	hash := hex.EncodeToString(sum[:])[:8]
+
+	leaseName := name
+	if readable := strings.Trim(invalidLeaseNameChars.ReplaceAllString(strings.ToLower(shardSelector), "-"), "-."); readable != "" {
+		leaseName += "-" + readable
+	}
+	if maxLength := validation.DNS1123SubdomainMaxLength - len(hash) - 1; len(leaseName) > maxLength {
+		leaseName = strings.TrimRight(leaseName[:maxLength], "-.")
+	}
+	return leaseName + "-" + hash
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestParseNamespaces(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{value: "", want: nil},
+		{value: "*", want: nil},
+		{value: "store", want: []string{"store"}},
+		{value: "store, news,,store", want: []string{"store", "news"}},
+		{value: "store,*", want: nil},
+	}
+	for _, tt := range tests {
+		if got := ParseNamespaces(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseNamespaces(%q) = %v, expected %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestShardLeaseName(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{selector: "", want: "waitingroomoperator"},
+		{selector: "lineq.io/shard=a", want: "waitingroomoperator-lineq.io-shard-a-496e9a1c"},
+		{selector: "lineq.io/shard = a", want: "waitingroomoperator-lineq.io-shard-a-496e9a1c"},
+		{selector: "lineq.io/shard in (a,b)", want: "waitingroomoperator-lineq.io-shard-in-a-b-51d255fb"},
+		{selector: "shard=a", want: "waitingroomoperator-shard-a-3d8dfb26"},
+		{selector: "shard!=a", want: "waitingroomoperator-shard-a-77a1ece5"},
+		{selector: "legacy", want: "waitingroomoperator-legacy-c49fea74"},
+		{selector: "!legacy", want: "waitingroomoperator-legacy-52ec25c1"},
+		{selector: "!Legacy", want: "waitingroomoperator-legacy-e2f0c932"},
+	}
+	for _, tt := range tests {
+		if got := ShardLeaseName("waitingroomoperator", tt.selector); got != tt.want {
+			t.Errorf("ShardLeaseName(%q) = %q, expected %q", tt.selector, got, tt.want)
+		}
+	}
+
+	long := ShardLeaseName("waitingroomoperator", "lineq.io/shard in ("+strings.Repeat("a", 300)+")")
+	if len(long) > validation.DNS1123SubdomainMaxLength || !regexp.MustCompile(`-[0-9a-f]{8}$`).MatchString(long) {
+		t.Errorf("expected a truncated lease name ending with the hash, got %q", long)
+	}
+}
@@ -2,32 +2,33 @@ package runner
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
+	"math"
+	"sync/atomic"
+	"time"
 
 	"github.com/gotway/gotway/pkg/log"
 	"github.com/hamedetemaad/lineq-operator/internal/config"
 	"github.com/hamedetemaad/lineq-operator/pkg/controller"
+	"github.com/hamedetemaad/lineq-operator/pkg/health"
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 )
 
-type ResponseBody struct {
-	Status               string `json:"status"`
-	Message              string `json:"message"`
-	RoomTableName        string `json:"lineq_room_table"`
-	UserTableName        string `json:"lineq_user_table"`
-	LineqSessionDuration int    `json:"lineq_session_duration"`
-}
-
 type Runner struct {
 	ctrl      *controller.Controller
 	clientset *kubernetes.Clientset
+	lineq     lineq.Client
 	config    config.Config
 	logger    log.Logger
+
+	leading atomic.Bool
 }
 
 func (r *Runner) Start(ctx context.Context) {
@@ -41,105 +42,93 @@ func (r *Runner) Start(ctx context.Context) {
 }
 
 func (r *Runner) runSingleNode(ctx context.Context) {
+	r.leading.Store(true)
+	defer r.leading.Store(false)
+
+	if err := r.bootstrap(ctx); err != nil {
+		r.logger.Fatal("error bootstrapping controller ", err)
+	}
 	if err := r.ctrl.Run(ctx, r.config.NumWorkers, r.config); err != nil {
 		r.logger.Fatal("error running controller ", err)
 	}
 }
 
-func (r *Runner) getCfg() {
-	url := fmt.Sprintf("http://%s:%d/getConfig", r.config.LineqHttpAddr, r.config.LineqHttpPort)
-
-	response, err := http.Get(url)
-	if err != nil {
-		r.logger.Infof("Error sending request: %v", err)
-		return
+func (r *Runner) bootstrap(ctx context.Context) error {
+	if r.config.HAProxy.ConfigWriter {
+		r.logger.Info("checking haproxy configmaps")
+		if err := r.checkHAProxy(ctx); err != nil {
+			return err
+		}
 	}
-	defer response.Body.Close()
-
-	var responseBody ResponseBody
 
-	err = json.NewDecoder(response.Body).Decode(&responseBody)
-	if err != nil {
-		r.logger.Infof("Error decoding JSON response: '%v'", err)
-		return
-	}
-
-	r.config.LineqSessionDuration = responseBody.LineqSessionDuration
-	r.config.RoomTableName = responseBody.RoomTableName
-	r.config.UserTableName = responseBody.UserTableName
-
-	r.logger.Infof("Response Status: '%s'", responseBody.Status)
-	r.logger.Infof("Response Message: '%s'", responseBody.Message)
+	r.logger.Info("bootstrapping lineq config")
+	return r.getCfg(ctx)
 }
 
-func (r *Runner) initCfg() {
-	cm, _ := r.clientset.CoreV1().ConfigMaps("haproxy-controller").Get(context.TODO(), "haproxy-kubernetes-ingress", metav1.GetOptions{})
-
-	if _, exists := cm.Annotations["initialized"]; exists {
-		return
+func (r *Runner) checkHAProxy(ctx context.Context) error {
+	for _, name := range []string{
+		r.config.HAProxy.ConfigMapName,
+		r.config.HAProxy.AuxConfigMapName,
+	} {
+		_, err := r.clientset.CoreV1().
+			ConfigMaps(r.config.HAProxy.Namespace).
+			Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf(
+				"haproxy configmap '%s/%s' not found, check HAPROXY_NAMESPACE, HAPROXY_CONFIGMAP and HAPROXY_AUX_CONFIGMAP",
+				r.config.HAProxy.Namespace,
+				name,
+			)
+		}
+		if err != nil {
+			return fmt.Errorf("error getting haproxy configmap '%s/%s' %v", r.config.HAProxy.Namespace, name, err)
+		}
 	}
-
-	cm.Annotations["initialized"] = "true"
-
-	config := `
-
-http-request set-var(txn.vwr_path) var(txn.host),concat('.vwr',txn.path),map(/etc/haproxy/maps/path-exact.map)
-http-request set-var(txn.has_cookie) req.cook_cnt(sessionid) if { var(txn.vwr_path) -m found }
-http-request set-var(txn.t2) uuid()  if { var(txn.vwr_path) -m found } !{ var(txn.has_cookie) -m int gt 0 }
-http-request set-var(txn.sessionid) req.cook(sessionid) if { var(txn.vwr_path) -m found }
-http-request set-var(txn.index) var(txn.host),regsub(\.,_,g),concat(,txn.path,),regsub(\/,_,g) if { var(txn.vwr_path) -m found }
-http-request track-sc0 var(txn.index) table %s if { var(txn.vwr_path) -m found }
-http-response add-header Set-Cookie "sessionid=%%[var(txn.t2)]; path=%%[var(txn.path)]" if { var(txn.vwr_path) -m found } !{ var(txn.has_cookie) -m int gt 0 }
-http-request track-sc1 var(txn.sessionid),concat('@',txn.index) table %s if { var(txn.vwr_path) -m found } { var(txn.has_cookie) -m int gt 0 }
-http-request track-sc1 var(txn.t2),concat('@',txn.index) table %s if { var(txn.vwr_path) -m found } !{ var(txn.has_cookie) -m int gt 0 }
-http-request sc-inc-gpc1(1) if { var(txn.vwr_path) -m found } { sc_get_gpc0(0) gt 0 } !{ sc_get_gpc1(1) eq 1 }
-use_backend %%[var(txn.path_match),field(1,.)] if !{ var(txn.vwr_path) -m found } !{ path_sub /lineq }
-use_backend %%[var(txn.vwr_path),field(1,.)] if { sc_get_gpc1(1) eq 1 } || { sc_get_gpc0(0) gt 0 }
-use_backend lineq
-
-`
-
-	config = fmt.Sprintf(config, r.config.RoomTableName, r.config.UserTableName, r.config.UserTableName)
-
-	cm.Data = map[string]string{
-		"frontend-config-snippet": config,
-	}
-
-	r.clientset.CoreV1().ConfigMaps("haproxy-controller").Update(context.TODO(), cm, metav1.UpdateOptions{})
+	return nil
 }
 
-func (r *Runner) initAuxCfg() {
-	auxCm, _ := r.clientset.CoreV1().ConfigMaps("haproxy-controller").Get(context.TODO(), "haproxy-auxiliary-configmap", metav1.GetOptions{})
-
-	if _, exists := auxCm.Annotations["initialized"]; exists {
-		return
+// getCfg fetches the LineQ config, retrying with backoff until
+// LineqConfigTimeout, since HAProxy can't be configured without it.
+func (r *Runner) getCfg(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.config.LineqConfigTimeout)
+	defer cancel()
+
+	var cfg lineq.Config
+	var lastErr error
+	backoff := wait.Backoff{
+		Duration: time.Second,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    math.MaxInt32,
+		Cap:      30 * time.Second,
 	}
-
-	auxCm.Annotations = map[string]string{
-		"initialized": "true",
+	err := wait.ExponentialBackoffWithContext(ctx, backoff, func(ctx context.Context) (bool, error) {
+		cfg, lastErr = r.lineq.GetConfig(ctx)
+		if lastErr == nil {
+			lastErr = cfg.Validate()
+		}
+		if lastErr != nil {
+			r.logger.Errorf("error getting lineq config %v, retrying", lastErr)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("error getting lineq config within %v %v", r.config.LineqConfigTimeout, lastErr)
 	}
 
-	config := `
-
-peers lineq
-  server local
-  server lineq %s:%d
-backend %s
-  stick-table type string size 10 expire 1d store gpc0 peers lineq
-backend %s
-  stick-table type string len 72 size 100k expire %dm store gpc1 peers lineq
-backend lineq
-  mode http
-  server lineq %s:%d
+	r.config.LineqSessionDuration = cfg.SessionDuration
+	r.config.RoomTableName = cfg.RoomTableName
+	r.config.UserTableName = cfg.UserTableName
 
-`
+	r.logger.Infof(
+		"lineq config: room table '%s' user table '%s' session duration '%d'",
+		cfg.RoomTableName,
+		cfg.UserTableName,
+		cfg.SessionDuration,
+	)
 
-	config = fmt.Sprintf(config, r.config.LineqTcpAddr, r.config.LineqTcpPort, r.config.RoomTableName, r.config.UserTableName, r.config.LineqSessionDuration, r.config.LineqHttpAddr, r.config.LineqHttpPort)
-
-	auxCm.Data = map[string]string{
-		"haproxy-auxiliary.cfg": config,
-	}
-	r.clientset.CoreV1().ConfigMaps("haproxy-controller").Update(context.TODO(), auxCm, metav1.UpdateOptions{})
+	return nil
 }
 
 func (r *Runner) runHA(ctx context.Context) {
@@ -166,9 +155,6 @@ func (r *Runner) runHA(ctx context.Context) {
 		Callbacks: leaderelection.LeaderCallbacks{
 			OnStartedLeading: func(ctx context.Context) {
 				r.logger.Info("start leading")
-				r.getCfg()
-				r.initAuxCfg()
-				r.initCfg()
 				r.runSingleNode(ctx)
 			},
 			OnStoppedLeading: func() {
@@ -185,15 +171,64 @@ func (r *Runner) runHA(ctx context.Context) {
 	})
 }
 
+// ReadinessChecks reports the state of the controller. Only the instance
+// running the controller is checked, HA standbys are ready while they wait
+// for the lease.
+func (r *Runner) ReadinessChecks() map[string]health.Check {
+	return map[string]health.Check{
+		"leader": func(ctx context.Context) (string, error) {
+			switch {
+			case !r.config.HA.Enabled:
+				return "standalone", nil
+			case r.leading.Load():
+				return "leading", nil
+			default:
+				return "standby", nil
+			}
+		},
+		"informers": r.leaderCheck(func(ctx context.Context) error {
+			if !r.ctrl.HasSynced() {
+				return errors.New("informer caches not synced")
+			}
+			return nil
+		}),
+		"lineq": func(ctx context.Context) (string, error) {
+			if !r.leading.Load() {
+				return "skipped, not leading", nil
+			}
+			return r.ctrl.CheckLineqConfig()
+		},
+		"haproxy": func(ctx context.Context) (string, error) {
+			if !r.config.HAProxy.ConfigWriter {
+				return "skipped, not the haproxy config writer", nil
+			}
+			return r.leaderCheck(func(ctx context.Context) error {
+				return r.ctrl.CheckHAProxyConfig()
+			})(ctx)
+		},
+	}
+}
+
+func (r *Runner) leaderCheck(check func(ctx context.Context) error) health.Check {
+	return func(ctx context.Context) (string, error) {
+		if !r.leading.Load() {
+			return "skipped, not leading", nil
+		}
+		return "", check(ctx)
+	}
+}
+
 func NewRunner(
 	ctrl *controller.Controller,
 	clientset *kubernetes.Clientset,
+	lineqClient lineq.Client,
 	config config.Config,
 	logger log.Logger,
 ) *Runner {
 	return &Runner{
 		ctrl:      ctrl,
 		clientset: clientset,
+		lineq:     lineqClient,
 		config:    config,
 		logger:    logger,
 	}
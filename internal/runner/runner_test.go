@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gotway/gotway/pkg/log"
+
+	"github.com/hamedetemaad/lineq-operator/internal/config"
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq"
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq/lineqtest"
+)
+
+func newTestRunner(t *testing.T, timeout time.Duration) (*Runner, *lineqtest.Server) {
+	t.Helper()
+	server := lineqtest.NewServer()
+	t.Cleanup(server.Close)
+	logger := log.NewLogger(log.Fields{}, "test", "error", io.Discard)
+	return NewRunner(
+		nil,
+		nil,
+		lineq.New(server.Options(), logger),
+		config.Config{LineqConfigTimeout: timeout},
+		logger,
+	), server
+}
+
+func TestGetCfgRetries(t *testing.T) {
+	r, server := newTestRunner(t, 10*time.Second)
+	server.SetConfig(lineq.Config{RoomTableName: "lineq_room", UserTableName: "lineq_user", SessionDuration: 5})
+	server.FailNext("getConfig", http.StatusServiceUnavailable)
+
+	if err := r.getCfg(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.config.RoomTableName != "lineq_room" || r.config.UserTableName != "lineq_user" || r.config.LineqSessionDuration != 5 {
+		t.Errorf("unexpected config %v", r.config)
+	}
+}
+
+func TestGetCfgDeadline(t *testing.T) {
+	r, server := newTestRunner(t, 1500*time.Millisecond)
+	server.SetConfig(lineq.Config{UserTableName: "lineq_user", SessionDuration: 5})
+
+	if err := r.getCfg(context.Background()); err == nil {
+		t.Fatal("expected error for an invalid lineq config")
+	}
+	if r.config.RoomTableName != "" {
+		t.Errorf("expected config to be left untouched, got %v", r.config)
+	}
+}
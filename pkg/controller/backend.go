@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"fmt"
+	"reflect"
+
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const backendServiceIndex = "backendService"
+
+type resolveError struct {
+	reason  string
+	message string
+}
+
+func (e *resolveError) Error() string {
+	return e.message
+}
+
+// backendServiceKey returns the namespace/name of the Service referenced by
+// backendRef, or an empty string when the room uses backendSvcAddr.
+func backendServiceKey(wr *wrv1alpha1.WaitingRoom) string {
+	ref := wr.Spec.BackendRef
+	if ref == nil {
+		return ""
+	}
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = wr.Namespace
+	}
+	return namespace + "/" + ref.Name
+}
+
+func indexByBackendService(obj interface{}) ([]string, error) {
+	wr, ok := obj.(*wrv1alpha1.WaitingRoom)
+	if !ok {
+		return nil, nil
+	}
+	key := backendServiceKey(wr)
+	if key == "" {
+		return nil, nil
+	}
+	return []string{key}, nil
+}
+
+func (c *Controller) resolveBackend(wr *wrv1alpha1.WaitingRoom) (netv1.IngressServiceBackend, error) {
+	ref := wr.Spec.BackendRef
+	if ref == nil {
+		return netv1.IngressServiceBackend{
+			Name: wr.Spec.BackendSvcAddr,
+			Port: netv1.ServiceBackendPort{
+				Number: int32(wr.Spec.BackendSvcPort),
+			},
+		}, nil
+	}
+
+	if ref.Namespace != "" && ref.Namespace != wr.Namespace {
+		return netv1.IngressServiceBackend{}, &resolveError{
+			reason:  "CrossNamespace",
+			message: fmt.Sprintf("service '%s/%s' is not in namespace '%s', ingress backends cannot cross namespaces", ref.Namespace, ref.Name, wr.Namespace),
+		}
+	}
+
+	obj, exists, err := c.svcInformer.GetByKey(backendServiceKey(wr))
+	if err != nil {
+		return netv1.IngressServiceBackend{}, fmt.Errorf("error getting service %v", err)
+	}
+	if !exists {
+		return netv1.IngressServiceBackend{}, &resolveError{
+			reason:  "ServiceNotFound",
+			message: fmt.Sprintf("service '%s' not found", backendServiceKey(wr)),
+		}
+	}
+	svc, ok := obj.(*corev1.Service)
+	if !ok {
+		return netv1.IngressServiceBackend{}, fmt.Errorf("unexpected object %v", obj)
+	}
+
+	port, err := resolvePort(svc, ref.Port)
+	if err != nil {
+		return netv1.IngressServiceBackend{}, err
+	}
+	return netv1.IngressServiceBackend{
+		Name: svc.Name,
+		Port: port,
+	}, nil
+}
+
+func resolvePort(svc *corev1.Service, port wrv1alpha1.BackendPort) (netv1.ServiceBackendPort, error) {
+	if port.Name == "" && port.Number == 0 {
+		if len(svc.Spec.Ports) != 1 {
+			return netv1.ServiceBackendPort{}, &resolveError{
+				reason:  "PortNotFound",
+				message: fmt.Sprintf("service '%s/%s' exposes %d ports, set backendRef.port", svc.Namespace, svc.Name, len(svc.Spec.Ports)),
+			}
+		}
+		return netv1.ServiceBackendPort{Number: svc.Spec.Ports[0].Port}, nil
+	}
+
+	for _, p := range svc.Spec.Ports {
+		if port.Name != "" && p.Name == port.Name {
+			return netv1.ServiceBackendPort{Name: p.Name}, nil
+		}
+		if port.Number != 0 && p.Port == port.Number {
+			return netv1.ServiceBackendPort{Number: p.Port}, nil
+		}
+	}
+
+	selector := port.Name
+	if selector == "" {
+		selector = fmt.Sprint(port.Number)
+	}
+	return netv1.ServiceBackendPort{}, &resolveError{
+		reason:  "PortNotFound",
+		message: fmt.Sprintf("service '%s/%s' has no port '%s'", svc.Namespace, svc.Name, selector),
+	}
+}
+
+func (c *Controller) addService(obj interface{}) {
+	c.enqueueServiceWaitingRooms(obj)
+}
+
+func (c *Controller) updateService(oldObj, newObj interface{}) {
+	oldSvc, ok := oldObj.(*corev1.Service)
+	if !ok {
+		c.logger.Errorf("unexpected object %v", oldObj)
+		return
+	}
+	newSvc, ok := newObj.(*corev1.Service)
+	if !ok {
+		c.logger.Errorf("unexpected object %v", newObj)
+		return
+	}
+	if reflect.DeepEqual(oldSvc.Spec.Ports, newSvc.Spec.Ports) {
+		return
+	}
+	c.enqueueServiceWaitingRooms(newSvc)
+}
+
+func (c *Controller) deleteService(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	c.enqueueServiceWaitingRooms(obj)
+}
+
+func (c *Controller) enqueueServiceWaitingRooms(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		c.logger.Errorf("error getting key %v", err)
+		return
+	}
+	objs, err := c.wrInformer.ByIndex(backendServiceIndex, key)
+	if err != nil {
+		c.logger.Errorf("error getting waiting rooms for service '%s' %v", key, err)
+		return
+	}
+	for _, obj := range objs {
+		wrKey, err := cache.MetaNamespaceKeyFunc(obj)
+		if err != nil {
+			c.logger.Errorf("error getting key %v", err)
+			continue
+		}
+		c.logger.Debugf("service '%s' changed, syncing waiting room '%s'", key, wrKey)
+		c.queue.Add(wrKey)
+	}
+}
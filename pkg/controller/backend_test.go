@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newService(name, namespace string, ports ...corev1.ServicePort) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       corev1.ServiceSpec{Ports: ports},
+	}
+}
+
+func newBackendRefRoom(name, namespace string, ref wrv1alpha1.BackendRef) *wrv1alpha1.WaitingRoom {
+	room := newWaitingRoom(name, namespace)
+	room.Spec.BackendSvcAddr = ""
+	room.Spec.BackendSvcPort = 0
+	room.Spec.BackendRef = &ref
+	return room
+}
+
+func TestResolveBackend(t *testing.T) {
+	tc := newTestController(t)
+	for _, svc := range []*corev1.Service{
+		newService("shop", "test", corev1.ServicePort{Name: "http", Port: 8080}),
+		newService("multi", "test",
+			corev1.ServicePort{Name: "http", Port: 80},
+			corev1.ServicePort{Name: "https", Port: 443},
+		),
+	} {
+		if err := tc.svcInformer.Indexer("test").Add(svc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		room   *wrv1alpha1.WaitingRoom
+		svc    string
+		number int32
+		port   string
+		reason string
+	}{
+		{
+			name:   "backendSvcAddr",
+			room:   newWaitingRoom("shop", "test"),
+			svc:    "shop",
+			number: 80,
+		},
+		{
+			name:   "single port",
+			room:   newBackendRefRoom("shop", "test", wrv1alpha1.BackendRef{Name: "shop"}),
+			svc:    "shop",
+			number: 8080,
+		},
+		{
+			name: "port name",
+			room: newBackendRefRoom("shop", "test", wrv1alpha1.BackendRef{
+				Name: "multi",
+				Port: wrv1alpha1.BackendPort{Name: "https"},
+			}),
+			svc:  "multi",
+			port: "https",
+		},
+		{
+			name: "port number",
+			room: newBackendRefRoom("shop", "test", wrv1alpha1.BackendRef{
+				Name:      "multi",
+				Namespace: "test",
+				Port:      wrv1alpha1.BackendPort{Number: 80},
+			}),
+			svc:    "multi",
+			number: 80,
+		},
+		{
+			name:   "ambiguous port",
+			room:   newBackendRefRoom("shop", "test", wrv1alpha1.BackendRef{Name: "multi"}),
+			reason: "PortNotFound",
+		},
+		{
+			name: "missing port",
+			room: newBackendRefRoom("shop", "test", wrv1alpha1.BackendRef{
+				Name: "shop",
+				Port: wrv1alpha1.BackendPort{Number: 9090},
+			}),
+			reason: "PortNotFound",
+		},
+		{
+			name:   "missing service",
+			room:   newBackendRefRoom("shop", "test", wrv1alpha1.BackendRef{Name: "cart"}),
+			reason: "ServiceNotFound",
+		},
+		{
+			name: "cross namespace",
+			room: newBackendRefRoom("shop", "test", wrv1alpha1.BackendRef{
+				Name:      "shop",
+				Namespace: "other",
+			}),
+			reason: "CrossNamespace",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, err := tc.resolveBackend(tt.room)
+			if tt.reason != "" {
+				var resolveErr *resolveError
+				if !errors.As(err, &resolveErr) || resolveErr.reason != tt.reason {
+					t.Fatalf("expected %s error, got %v", tt.reason, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if backend.Name != tt.svc || backend.Port.Number != tt.number || backend.Port.Name != tt.port {
+				t.Errorf("unexpected backend %+v", backend)
+			}
+		})
+	}
+}
+
+func TestProcessAddWaitingRoomUnresolvedBackend(t *testing.T) {
+	room := newBackendRefRoom("shop", "test", wrv1alpha1.BackendRef{Name: "shop"})
+	tc := newTestController(t, room)
+	ctx := context.Background()
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ings, err := tc.kubeClientSet.NetworkingV1().Ingresses("test").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ings.Items) != 0 {
+		t.Errorf("expected no ingress, got %v", ings.Items)
+	}
+
+	got := tc.getWaitingRoom(t, "test", "shop")
+	condition := meta.FindStatusCondition(got.Status.Conditions, wrv1alpha1.ConditionBackendResolved)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != "ServiceNotFound" {
+		t.Errorf("expected BackendResolved to be false with ServiceNotFound, got %v", condition)
+	}
+	if meta.IsStatusConditionTrue(got.Status.Conditions, wrv1alpha1.ConditionReady) {
+		t.Error("expected room not to be ready")
+	}
+
+	svc := newService("shop", "test", corev1.ServicePort{Name: "http", Port: 8080})
+	if err := tc.svcInformer.Indexer("test").Add(svc); err != nil {
+		t.Fatal(err)
+	}
+	tc.addService(svc)
+	if tc.queue.Len() != 1 {
+		t.Fatalf("expected waiting room to be queued, got %d items", tc.queue.Len())
+	}
+	tc.processQueue(t)
+
+	ing, err := tc.kubeClientSet.NetworkingV1().Ingresses("test").Get(ctx, "shop", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting ingress: %v", err)
+	}
+	assertIngress(t, ing, "example.com.vwr", "/checkout", "shop", 8080)
+
+	got = tc.getWaitingRoom(t, "test", "shop")
+	for _, c := range []string{
+		wrv1alpha1.ConditionBackendResolved,
+		wrv1alpha1.ConditionIngressReady,
+		wrv1alpha1.ConditionReady,
+	} {
+		if !meta.IsStatusConditionTrue(got.Status.Conditions, c) {
+			t.Errorf("expected condition %s to be true, got %v", c, got.Status.Conditions)
+		}
+	}
+}
+
+func TestUpdateServiceIgnoresUnchangedPorts(t *testing.T) {
+	room := newBackendRefRoom("shop", "test", wrv1alpha1.BackendRef{Name: "shop"})
+	tc := newTestController(t)
+	if err := tc.wrInformer.Indexer("test").Add(room); err != nil {
+		t.Fatal(err)
+	}
+
+	oldSvc := newService("shop", "test", corev1.ServicePort{Port: 80})
+	newSvc := oldSvc.DeepCopy()
+	newSvc.Labels = map[string]string{"team": "shop"}
+	tc.updateService(oldSvc, newSvc)
+	if tc.queue.Len() != 0 {
+		t.Errorf("expected no items queued, got %d", tc.queue.Len())
+	}
+
+	newSvc.Spec.Ports[0].Port = 8080
+	tc.updateService(oldSvc, newSvc)
+	if tc.queue.Len() != 1 {
+		t.Errorf("expected waiting room to be queued, got %d items", tc.queue.Len())
+	}
+}
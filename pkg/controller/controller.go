@@ -3,69 +3,147 @@ package controller
 import (
 	"context"
 	"errors"
+	"reflect"
+	"sync"
 	"time"
 
 	"github.com/gotway/gotway/pkg/log"
 
+	"github.com/hamedetemaad/lineq-operator/pkg/haproxy"
+	"github.com/hamedetemaad/lineq-operator/pkg/informer"
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq"
 	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
 	wrv1alpha1clientset "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1/apis/clientset/versioned"
+	wrscheme "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1/apis/clientset/versioned/scheme"
 	wrinformers "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1/apis/informers/externalversions"
 
 	"github.com/hamedetemaad/lineq-operator/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 )
 
+// fieldManager owns every field the operator writes with server-side apply.
+const fieldManager = "lineq-operator"
+
+// The event recorder resolves the kind of WaitingRooms from the client-go
+// scheme.
+func init() {
+	utilruntime.Must(wrscheme.AddToScheme(scheme.Scheme))
+}
+
 type Controller struct {
 	kubeClientSet kubernetes.Interface
+	wrClientSet   wrv1alpha1clientset.Interface
 
-	wrInformer  cache.SharedIndexInformer
-	ingInformer cache.SharedIndexInformer
+	lineq lineq.Client
 
-	queue workqueue.RateLimitingInterface
+	wrInformer *informer.Namespaced
+	// roomInformer holds the WaitingRooms of every namespace and shard,
+	// rendered into the HAProxy frontend by the config writer. It is
+	// wrInformer when the instance already watches all of them or doesn't
+	// write the HAProxy config.
+	roomInformer *informer.Namespaced
+	ingInformer  *informer.Namespaced
+	svcInformer  *informer.Namespaced
+	cmInformer   cache.SharedIndexInformer
+
+	queue       workqueue.RateLimitingInterface
+	configQueue workqueue.RateLimitingInterface
+
+	eventBroadcaster record.EventBroadcaster
+	recorder         record.EventRecorder
 
 	namespace string
+	haproxy   config.HAProxy
 
 	logger log.Logger
 
-	config config.Config
+	config   config.Config
+	configMu sync.RWMutex
+	// lineqErr is the result of the last periodic LineQ config sync, guarded
+	// by configMu.
+	lineqErr error
+
+	// haproxyRenderer is rebuilt only when the templates it was parsed from change.
+	haproxyRenderer  *haproxy.Renderer
+	haproxyTemplates map[string]string
+	rendererMu       sync.Mutex
+
+	// roomSeries holds the label values published for each room, by key.
+	roomSeries map[string][]string
+	// roomAdmitted holds the last admitted users total LineQ reported for
+	// each room, by key.
+	roomAdmitted map[string]int64
 }
 
 func (c *Controller) Run(ctx context.Context, numWorkers int, config config.Config) error {
+	c.configMu.Lock()
 	c.config = config
+	c.configMu.Unlock()
 	defer utilruntime.HandleCrash()
 	defer c.queue.ShutDown()
+	defer c.configQueue.ShutDown()
 
 	c.logger.Info("starting controller")
 
+	c.eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: c.kubeClientSet.CoreV1().Events(metav1.NamespaceAll),
+	})
+	defer c.eventBroadcaster.Shutdown()
+
 	c.logger.Info("starting informers")
-	for _, i := range []cache.SharedIndexInformer{
-		c.wrInformer,
-		c.ingInformer,
-	} {
-		go i.Run(ctx.Done())
+	c.wrInformer.Run(ctx.Done())
+	c.ingInformer.Run(ctx.Done())
+	c.svcInformer.Run(ctx.Done())
+	if c.haproxy.ConfigWriter {
+		if c.roomInformer != c.wrInformer {
+			c.roomInformer.Run(ctx.Done())
+		}
+		go c.cmInformer.Run(ctx.Done())
 	}
 
 	c.logger.Info("waiting for informer caches to sync")
-	if !cache.WaitForCacheSync(ctx.Done(), []cache.InformerSynced{
-		c.wrInformer.HasSynced,
-		c.ingInformer.HasSynced,
-	}...) {
+	if !cache.WaitForCacheSync(ctx.Done(), c.HasSynced) {
 		err := errors.New("failed to wait for informers caches to sync")
 		utilruntime.HandleError(err)
 		return err
 	}
 
+	if c.haproxy.ConfigWriter {
+		c.enqueueHAProxyConfigs()
+		go wait.Until(func() {
+			c.runConfigWorker(ctx)
+		}, time.Second, ctx.Done())
+	} else {
+		c.logger.Info("not the haproxy config writer, leaving haproxy configmaps alone")
+	}
+
 	c.logger.Infof("starting %d workers", numWorkers)
 	for i := 0; i < numWorkers; i++ {
 		go wait.Until(func() {
 			c.runWorker(ctx)
 		}, time.Second, ctx.Done())
 	}
+
+	c.logger.Infof("syncing room stats every %v", config.StatsSyncInterval)
+	go wait.Until(func() {
+		c.syncStats(ctx)
+	}, config.StatsSyncInterval, ctx.Done())
+
+	c.logger.Infof("syncing lineq config every %v", config.LineqConfigSyncInterval)
+	go wait.Until(func() {
+		c.syncLineqConfig(ctx)
+	}, config.LineqConfigSyncInterval, ctx.Done())
+
 	c.logger.Info("controller ready")
 
 	<-ctx.Done()
@@ -75,52 +153,174 @@ func (c *Controller) Run(ctx context.Context, numWorkers int, config config.Conf
 }
 
 func (c *Controller) addWaitingRoom(obj interface{}) {
-	c.logger.Debug("adding waiting room")
-	wr, ok := obj.(*wrv1alpha1.WaitingRoom)
+	c.enqueue(obj)
+}
+
+func (c *Controller) updateWaitingRoom(oldObj, newObj interface{}) {
+	oldWr, ok := oldObj.(*wrv1alpha1.WaitingRoom)
 	if !ok {
-		c.logger.Errorf("unexpected object %v", obj)
+		c.logger.Errorf("unexpected object %v", oldObj)
 		return
 	}
-	c.queue.Add(event{
-		eventType: addWaitingRoom,
-		newObj:    wr.DeepCopy(),
-	})
+	newWr, ok := newObj.(*wrv1alpha1.WaitingRoom)
+	if !ok {
+		c.logger.Errorf("unexpected object %v", newObj)
+		return
+	}
+	if statusOnlyChange(oldWr, newWr) {
+		return
+	}
+	c.enqueue(newWr)
+}
+
+func (c *Controller) deleteWaitingRoom(obj interface{}) {
+	c.enqueue(obj)
+}
+
+// statusOnlyChange reports whether an update only touched the status, which
+// the controller writes itself. Resyncs keep the same resource version and
+// are always reconciled.
+func statusOnlyChange(oldWr, newWr *wrv1alpha1.WaitingRoom) bool {
+	return oldWr.ResourceVersion != newWr.ResourceVersion &&
+		oldWr.Generation == newWr.Generation &&
+		newWr.DeletionTimestamp.Equal(oldWr.DeletionTimestamp) &&
+		reflect.DeepEqual(oldWr.Finalizers, newWr.Finalizers)
 }
 
 func New(
 	kubeClientSet kubernetes.Interface,
 	wrClientSet wrv1alpha1clientset.Interface,
+	lineqClient lineq.Client,
 	namespace string,
+	watchNamespaces []string,
+	shardSelector string,
+	haproxy config.HAProxy,
 	logger log.Logger,
 ) *Controller {
+	newWrInformer := func(namespaces []string, selector string) *informer.Namespaced {
+		return informer.NewNamespaced(namespaces, func(ns string) cache.SharedIndexInformer {
+			factory := wrinformers.NewSharedInformerFactoryWithOptions(
+				wrClientSet,
+				10*time.Second,
+				wrinformers.WithNamespace(ns),
+				wrinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+					options.LabelSelector = selector
+				}),
+			)
+			return factory.Lineq().V1alpha1().WaitingRooms().Informer()
+		})
+	}
+	// The shard selector is applied by the API server, each shard only
+	// caches its own rooms. The HAProxy frontend is shared by every tenant
+	// and shard, its writer caches the rooms of the whole cluster.
+	wrInformer := newWrInformer(watchNamespaces, shardSelector)
+	roomInformer := wrInformer
+	if haproxy.ConfigWriter && (len(watchNamespaces) > 0 || shardSelector != "") {
+		roomInformer = newWrInformer(nil, "")
+	}
 
-	wrInformerFactory := wrinformers.NewSharedInformerFactory(
-		wrClientSet,
+	kubeInformerFactories := make(map[string]kubeinformers.SharedInformerFactory)
+	kubeInformerFactory := func(ns string) kubeinformers.SharedInformerFactory {
+		factory, ok := kubeInformerFactories[ns]
+		if !ok {
+			factory = kubeinformers.NewSharedInformerFactoryWithOptions(
+				kubeClientSet,
+				10*time.Second,
+				kubeinformers.WithNamespace(ns),
+			)
+			kubeInformerFactories[ns] = factory
+		}
+		return factory
+	}
+	ingInformer := informer.NewNamespaced(watchNamespaces, func(ns string) cache.SharedIndexInformer {
+		return kubeInformerFactory(ns).Networking().V1().Ingresses().Informer()
+	})
+	svcInformer := informer.NewNamespaced(watchNamespaces, func(ns string) cache.SharedIndexInformer {
+		return kubeInformerFactory(ns).Core().V1().Services().Informer()
+	})
+
+	haproxyInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(
+		kubeClientSet,
 		10*time.Second,
+		kubeinformers.WithNamespace(haproxy.Namespace),
 	)
-	wrInformer := wrInformerFactory.Lineq().V1alpha1().WaitingRooms().Informer()
+	cmInformer := haproxyInformerFactory.Core().V1().ConfigMaps().Informer()
 
-	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClientSet, 10*time.Second)
-	ingInformer := kubeInformerFactory.Networking().V1().Ingresses().Informer()
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), waitingRoomQueueName)
+	configQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), haproxyConfigQueueName)
 
-	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	eventBroadcaster := record.NewBroadcaster()
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: fieldManager})
 
 	ctrl := &Controller{
 		kubeClientSet: kubeClientSet,
+		wrClientSet:   wrClientSet,
+
+		lineq: lineqClient,
+
+		wrInformer:   wrInformer,
+		roomInformer: roomInformer,
+		ingInformer:  ingInformer,
+		svcInformer:  svcInformer,
+		cmInformer:   cmInformer,
 
-		wrInformer:  wrInformer,
-		ingInformer: ingInformer,
+		queue:       queue,
+		configQueue: configQueue,
 
-		queue: queue,
+		eventBroadcaster: eventBroadcaster,
+		recorder:         recorder,
 
 		namespace: namespace,
+		haproxy:   haproxy,
 
 		logger: logger,
+
+		roomSeries:   make(map[string][]string),
+		roomAdmitted: make(map[string]int64),
 	}
 
-	wrInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: ctrl.addWaitingRoom,
-	})
+	if err := wrInformer.AddIndexers(cache.Indexers{
+		backendServiceIndex: indexByBackendService,
+	}); err != nil {
+		logger.Errorf("error adding waiting room indexers %v", err)
+	}
+	if err := wrInformer.AddEventHandler(countEvents("waitingroom", cache.ResourceEventHandlerFuncs{
+		AddFunc:    ctrl.addWaitingRoom,
+		UpdateFunc: ctrl.updateWaitingRoom,
+		DeleteFunc: ctrl.deleteWaitingRoom,
+	})); err != nil {
+		logger.Errorf("error adding waiting room event handler %v", err)
+	}
+	if err := ingInformer.AddEventHandler(countEvents("ingress", cache.ResourceEventHandlerFuncs{
+		UpdateFunc: ctrl.updateIngress,
+		DeleteFunc: ctrl.deleteIngress,
+	})); err != nil {
+		logger.Errorf("error adding ingress event handler %v", err)
+	}
+	if err := svcInformer.AddEventHandler(countEvents("service", cache.ResourceEventHandlerFuncs{
+		AddFunc:    ctrl.addService,
+		UpdateFunc: ctrl.updateService,
+		DeleteFunc: ctrl.deleteService,
+	})); err != nil {
+		logger.Errorf("error adding service event handler %v", err)
+	}
+	// Only the config writer renders the HAProxy configmaps.
+	if haproxy.ConfigWriter {
+		if err := roomInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    ctrl.addRoom,
+			UpdateFunc: ctrl.updateRoom,
+			DeleteFunc: ctrl.deleteRoom,
+		}); err != nil {
+			logger.Errorf("error adding haproxy room event handler %v", err)
+		}
+		if _, err := cmInformer.AddEventHandler(countEvents("configmap", cache.ResourceEventHandlerFuncs{
+			AddFunc:    ctrl.addConfigMap,
+			UpdateFunc: ctrl.updateConfigMap,
+			DeleteFunc: ctrl.deleteConfigMap,
+		})); err != nil {
+			logger.Errorf("error adding configmap event handler %v", err)
+		}
+	}
 
 	return ctrl
 }
@@ -0,0 +1,253 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/gotway/gotway/pkg/log"
+
+	"github.com/hamedetemaad/lineq-operator/internal/config"
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq"
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq/lineqtest"
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	wrfake "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1/apis/clientset/versioned/fake"
+
+	netv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+type testController struct {
+	*Controller
+	kubeClientSet *kubefake.Clientset
+	wrClientSet   *wrfake.Clientset
+	lineqServer   *lineqtest.Server
+	recorder      *record.FakeRecorder
+}
+
+func newTestController(t *testing.T, wrObjects ...runtime.Object) *testController {
+	t.Helper()
+	return newShardedTestController(t, "", wrObjects...)
+}
+
+func newShardedTestController(t *testing.T, shardSelector string, wrObjects ...runtime.Object) *testController {
+	t.Helper()
+	return newScopedTestController(t, nil, shardSelector, wrObjects...)
+}
+
+func newScopedTestController(t *testing.T, watchNamespaces []string, shardSelector string, wrObjects ...runtime.Object) *testController {
+	t.Helper()
+
+	lineqServer := lineqtest.NewServer()
+	t.Cleanup(lineqServer.Close)
+
+	kubeClientSet := kubefake.NewSimpleClientset()
+	kubeClientSet.PrependReactor("patch", "ingresses", applyReactor(kubeClientSet.Tracker(), func() runtime.Object {
+		return &netv1.Ingress{}
+	}))
+	wrClientSet := wrfake.NewSimpleClientset(wrObjects...)
+	logger := log.NewLogger(log.Fields{}, "test", "error", io.Discard)
+
+	ctrl := New(
+		kubeClientSet,
+		wrClientSet,
+		lineq.New(lineqServer.Options(), logger),
+		metav1.NamespaceDefault,
+		watchNamespaces,
+		shardSelector,
+		config.HAProxy{
+			Namespace:        "haproxy-controller",
+			ConfigMapName:    "haproxy-kubernetes-ingress",
+			AuxConfigMapName: "haproxy-auxiliary-configmap",
+			ConfigWriter:     true,
+		},
+		logger,
+	)
+
+	recorder := record.NewFakeRecorder(100)
+	ctrl.recorder = recorder
+
+	return &testController{
+		Controller:    ctrl,
+		kubeClientSet: kubeClientSet,
+		wrClientSet:   wrClientSet,
+		lineqServer:   lineqServer,
+		recorder:      recorder,
+	}
+}
+
+// applyReactor creates objects on server-side apply, which the fake clientset
+// only supports for objects that already exist.
+func applyReactor(tracker k8stesting.ObjectTracker, newObj func() runtime.Object) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch, ok := action.(k8stesting.PatchAction)
+		if !ok || patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		_, err := tracker.Get(action.GetResource(), action.GetNamespace(), patch.GetName())
+		if !apierrors.IsNotFound(err) {
+			return false, nil, nil
+		}
+		obj := newObj()
+		if err := json.Unmarshal(patch.GetPatch(), obj); err != nil {
+			return true, nil, err
+		}
+		if err := tracker.Create(action.GetResource(), obj, action.GetNamespace()); err != nil {
+			return true, nil, err
+		}
+		return true, obj, nil
+	}
+}
+
+// assertApplied fails if resource, or one of its subresources, was written
+// with anything other than server-side apply.
+func assertApplied(t *testing.T, actions []k8stesting.Action, resource, subresource string) {
+	t.Helper()
+	applied := false
+	for _, action := range actions {
+		if action.GetResource().Resource != resource || action.GetSubresource() != subresource {
+			continue
+		}
+		switch a := action.(type) {
+		case k8stesting.CreateAction, k8stesting.UpdateAction:
+			t.Errorf("expected %s to be applied, got %s", resource, action.GetVerb())
+		case k8stesting.PatchAction:
+			if a.GetPatchType() != types.ApplyPatchType {
+				t.Errorf("expected %s to be applied, got %s patch", resource, a.GetPatchType())
+			}
+			applied = true
+		}
+	}
+	if !applied {
+		t.Errorf("expected %s to be applied", resource)
+	}
+}
+
+// reconcileRoom copies the waiting room from the fake clientset into the
+// informer, as a running informer would, and reconciles it.
+func (tc *testController) reconcileRoom(t *testing.T, namespace, name string) error {
+	t.Helper()
+	key := namespace + "/" + name
+	wr, err := tc.wrClientSet.LineqV1alpha1().
+		WaitingRooms(namespace).
+		Get(context.Background(), name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		obj, exists, _ := tc.wrInformer.Indexer(namespace).GetByKey(key)
+		if exists {
+			if err := tc.wrInformer.Indexer(namespace).Delete(obj); err != nil {
+				t.Fatal(err)
+			}
+		}
+	case err != nil:
+		t.Fatalf("error getting waiting room: %v", err)
+	default:
+		if err := tc.wrInformer.Indexer(namespace).Update(wr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tc.reconcile(context.Background(), key)
+}
+
+// processQueue reconciles every queued key.
+func (tc *testController) processQueue(t *testing.T) {
+	t.Helper()
+	for tc.queue.Len() > 0 {
+		item, _ := tc.queue.Get()
+		key := item.(string)
+		namespace, name, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tc.reconcileRoom(t, namespace, name); err != nil {
+			t.Fatalf("unexpected error reconciling '%s': %v", key, err)
+		}
+		tc.queue.Forget(item)
+		tc.queue.Done(item)
+	}
+}
+
+func (tc *testController) updateWaitingRoomFixture(t *testing.T, wr *wrv1alpha1.WaitingRoom) {
+	t.Helper()
+	_, err := tc.wrClientSet.LineqV1alpha1().
+		WaitingRooms(wr.Namespace).
+		Update(context.Background(), wr, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("error updating waiting room: %v", err)
+	}
+}
+
+func (tc *testController) getWaitingRoom(t *testing.T, namespace, name string) *wrv1alpha1.WaitingRoom {
+	t.Helper()
+	wr, err := tc.wrClientSet.LineqV1alpha1().
+		WaitingRooms(namespace).
+		Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting waiting room: %v", err)
+	}
+	return wr
+}
+
+func newWaitingRoom(name, namespace string) *wrv1alpha1.WaitingRoom {
+	return &wrv1alpha1.WaitingRoom{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Namespace:  namespace,
+			UID:        types.UID("uid-" + name),
+			Generation: 1,
+		},
+		Spec: wrv1alpha1.WaitingRoomSpec{
+			Path:           "/checkout",
+			ActiveUsers:    20,
+			Schema:         "http",
+			Host:           "example.com",
+			BackendSvcAddr: "shop",
+			BackendSvcPort: 80,
+		},
+	}
+}
+
+func TestShardSelector(t *testing.T) {
+	inShard := newWaitingRoom("shop", "test")
+	inShard.Labels = map[string]string{"lineq.io/shard": "a"}
+	otherShard := newWaitingRoom("blog", "test")
+	otherShard.Labels = map[string]string{"lineq.io/shard": "b"}
+	otherShard.Spec.Path = "/blog"
+	unlabelled := newWaitingRoom("news", "test")
+	unlabelled.Spec.Path = "/news"
+	tc := newShardedTestController(t, "lineq.io/shard=a", inShard, otherShard, unlabelled)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	tc.wrInformer.Run(stopCh)
+	tc.roomInformer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, tc.wrInformer.HasSynced, tc.roomInformer.HasSynced) {
+		t.Fatal("informer caches did not sync")
+	}
+
+	if objs := tc.wrInformer.List(); len(objs) != 1 || objs[0].(*wrv1alpha1.WaitingRoom).Name != "shop" {
+		t.Errorf("expected only the room of the shard to be cached, got %d rooms", len(objs))
+	}
+	if tc.queue.Len() != 1 {
+		t.Errorf("expected only the room of the shard to be queued, got %d keys", tc.queue.Len())
+	}
+	if err := tc.reconcile(context.Background(), "test/shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertLineqCalls(t, tc.lineqServer.Calls(),
+		lineqCall("create", checkoutRoom(20)),
+	)
+
+	// The HAProxy frontend still routes the rooms of every shard.
+	if rooms, _ := tc.haproxyRooms(); len(rooms) != 3 {
+		t.Errorf("expected the rooms of every shard in the haproxy config, got %+v", rooms)
+	}
+}
@@ -1,12 +0,0 @@
-package controller
-
-type eventType string
-
-const (
-	addWaitingRoom             eventType = "addWaitingRoom"
-)
-
-type event struct {
-	eventType      eventType
-	oldObj, newObj interface{}
-}
\ No newline at end of file
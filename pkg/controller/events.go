@@ -0,0 +1,60 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+)
+
+const (
+	reasonValidationFailed     = "ValidationFailed"
+	reasonRegistered           = "Registered"
+	reasonRegistrationFailed   = "RegistrationFailed"
+	reasonIngressSynced        = "IngressSynced"
+	reasonIngressSyncFailed    = "IngressSyncFailed"
+	reasonRoomMissing          = "RoomMissing"
+	reasonHAProxyConfigUpdated = "HAProxyConfigUpdated"
+	reasonRetriesExhausted     = "RetriesExhausted"
+)
+
+// objectFunc returns the object stored under a queue key, so events can be
+// attached to it. It returns nil when the object is gone.
+type objectFunc func(key string) runtime.Object
+
+func (c *Controller) waitingRoomObject(key string) runtime.Object {
+	obj, exists, err := c.wrInformer.GetByKey(key)
+	if err != nil || !exists {
+		return nil
+	}
+	wr, ok := obj.(*wrv1alpha1.WaitingRoom)
+	if !ok {
+		return nil
+	}
+	return wr
+}
+
+func (c *Controller) configMapObject(name string) runtime.Object {
+	obj, exists, err := c.cmInformer.GetIndexer().GetByKey(c.haproxy.Namespace + "/" + name)
+	if err != nil || !exists {
+		return nil
+	}
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return nil
+	}
+	return cm
+}
+
+// conditionCurrent reports whether the status already records the condition
+// for the current generation, events are only emitted on transitions so
+// resyncs don't repeat them.
+func conditionCurrent(wr *wrv1alpha1.WaitingRoom, conditionType string, status metav1.ConditionStatus, reason string) bool {
+	condition := meta.FindStatusCondition(wr.Status.Conditions, conditionType)
+	return condition != nil &&
+		condition.Status == status &&
+		condition.Reason == reason &&
+		condition.ObservedGeneration == wr.Generation
+}
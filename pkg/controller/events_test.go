@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestReconcileEvents(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	tc := newTestController(t, room)
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEvents(t, tc.recorder, "Normal Registered", "Normal IngressSynced")
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEvents(t, tc.recorder)
+}
+
+func TestBackendNotResolvedEvent(t *testing.T) {
+	room := newBackendRefRoom("shop", "test", wrv1alpha1.BackendRef{Name: "missing"})
+	tc := newTestController(t, room)
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEvents(t, tc.recorder, "Normal Registered", "Warning ServiceNotFound")
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEvents(t, tc.recorder)
+}
+
+func TestValidationFailedEvent(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	room.Spec.Schema = wrv1alpha1.SchemaHTTPS
+	tc := newTestController(t, room)
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEvents(t, tc.recorder, "Warning ValidationFailed")
+	assertLineqCalls(t, tc.lineqServer.Calls())
+
+	got := tc.getWaitingRoom(t, "test", "shop")
+	condition := meta.FindStatusCondition(got.Status.Conditions, wrv1alpha1.ConditionSpecValid)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != reasonValidationFailed {
+		t.Errorf("expected %s to be false with reason %s, got %v", wrv1alpha1.ConditionSpecValid, reasonValidationFailed, condition)
+	}
+	if !meta.IsStatusConditionFalse(got.Status.Conditions, wrv1alpha1.ConditionReady) {
+		t.Errorf("expected %s to be false, got %v", wrv1alpha1.ConditionReady, got.Status.Conditions)
+	}
+	if _, err := tc.kubeClientSet.NetworkingV1().
+		Ingresses("test").
+		Get(context.Background(), "shop", metav1.GetOptions{}); err == nil {
+		t.Error("expected no ingress for an invalid spec")
+	}
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEvents(t, tc.recorder)
+}
+
+func TestProcessNextItemRetriesExhausted(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	tc := newTestController(t)
+	if err := tc.wrInformer.Indexer("test").Add(room); err != nil {
+		t.Fatal(err)
+	}
+
+	failing := func(ctx context.Context, key string) error {
+		return errors.New("lineq unavailable")
+	}
+	tc.queue.Add("test/shop")
+	for i := 0; i <= maxRetries; i++ {
+		tc.processNextItem(context.Background(), waitingRoomQueueName, tc.queue, failing, tc.waitingRoomObject)
+	}
+
+	assertEvents(t, tc.recorder, "Warning RetriesExhausted")
+	if tc.queue.Len() != 0 {
+		t.Errorf("expected key to be dropped, got %d items", tc.queue.Len())
+	}
+}
+
+// assertEvents checks the events recorded since the last call, by type and
+// reason, in order.
+func assertEvents(t *testing.T, recorder *record.FakeRecorder, want ...string) {
+	t.Helper()
+	var got []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			fields := strings.SplitN(event, " ", 3)
+			got = append(got, fields[0]+" "+fields[1])
+			continue
+		default:
+		}
+		break
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected events %v, got %v", want, got)
+	}
+}
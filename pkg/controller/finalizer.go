@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	wr "github.com/hamedetemaad/lineq-operator/pkg/waitingroom"
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func hasFinalizer(waitingRoom *wrv1alpha1.WaitingRoom) bool {
+	for _, f := range waitingRoom.Finalizers {
+		if f == wr.WaitingRoomFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Controller) addFinalizer(ctx context.Context, waitingRoom *wrv1alpha1.WaitingRoom) error {
+	if hasFinalizer(waitingRoom) {
+		return nil
+	}
+
+	latest, err := c.wrClientSet.LineqV1alpha1().
+		WaitingRooms(waitingRoom.Namespace).
+		Get(ctx, waitingRoom.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if hasFinalizer(latest) {
+		return nil
+	}
+
+	return c.patchFinalizers(ctx, latest, append(latest.Finalizers, wr.WaitingRoomFinalizer))
+}
+
+func (c *Controller) removeFinalizer(ctx context.Context, waitingRoom *wrv1alpha1.WaitingRoom) error {
+	latest, err := c.wrClientSet.LineqV1alpha1().
+		WaitingRooms(waitingRoom.Namespace).
+		Get(ctx, waitingRoom.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	finalizers := make([]string, 0, len(latest.Finalizers))
+	for _, f := range latest.Finalizers {
+		if f != wr.WaitingRoomFinalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	if len(finalizers) == len(latest.Finalizers) {
+		return nil
+	}
+
+	return c.patchFinalizers(ctx, latest, finalizers)
+}
+
+// patchFinalizers replaces the finalizers with a merge patch. The patch
+// carries the resourceVersion the list was built from, so a concurrent change
+// to the finalizers fails with a conflict instead of being overwritten.
+func (c *Controller) patchFinalizers(ctx context.Context, latest *wrv1alpha1.WaitingRoom, finalizers []string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"finalizers":      finalizers,
+			"resourceVersion": latest.ResourceVersion,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding finalizers %v", err)
+	}
+
+	_, err = c.wrClientSet.LineqV1alpha1().
+		WaitingRooms(latest.Namespace).
+		Patch(ctx, latest.Name, types.MergePatchType, patch, metav1.PatchOptions{FieldManager: fieldManager})
+
+	return err
+}
@@ -0,0 +1,345 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hamedetemaad/lineq-operator/internal/config"
+	"github.com/hamedetemaad/lineq-operator/pkg/haproxy"
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq"
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	"github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1/validation"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	frontendConfigKey = "frontend-config-snippet"
+	auxConfigKey      = "haproxy-auxiliary.cfg"
+
+	configHashAnnotation = "lineq.io/config-hash"
+)
+
+func (c *Controller) renderer() (*haproxy.Renderer, error) {
+	var overrides map[string]string
+	if name := c.haproxy.TemplateConfigMapName; name != "" {
+		obj, exists, err := c.cmInformer.GetIndexer().GetByKey(c.haproxy.Namespace + "/" + name)
+		if err != nil {
+			return nil, fmt.Errorf("error getting template configmap %v", err)
+		}
+		if exists {
+			cm, ok := obj.(*corev1.ConfigMap)
+			if !ok {
+				return nil, fmt.Errorf("unexpected object %v", obj)
+			}
+			overrides = cm.Data
+		}
+	}
+
+	c.rendererMu.Lock()
+	defer c.rendererMu.Unlock()
+	if c.haproxyRenderer != nil && reflect.DeepEqual(c.haproxyTemplates, overrides) {
+		return c.haproxyRenderer, nil
+	}
+	renderer, err := haproxy.New(overrides)
+	if err != nil {
+		return nil, err
+	}
+	c.haproxyRenderer = renderer
+	c.haproxyTemplates = overrides
+	return renderer, nil
+}
+
+func haproxyValues(cfg config.Config) haproxy.Values {
+	return haproxy.Values{
+		LineqTcpAddr:         cfg.LineqTcpAddr,
+		LineqTcpPort:         cfg.LineqTcpPort,
+		LineqHttpAddr:        cfg.LineqHttpAddr,
+		LineqHttpPort:        cfg.LineqHttpPort,
+		RoomTableName:        cfg.RoomTableName,
+		UserTableName:        cfg.UserTableName,
+		LineqSessionDuration: cfg.LineqSessionDuration,
+	}
+}
+
+func (c *Controller) desiredHAProxyConfig(name string) (string, string, bool, error) {
+	var key string
+	switch name {
+	case c.haproxy.ConfigMapName:
+		key = frontendConfigKey
+	case c.haproxy.AuxConfigMapName:
+		key = auxConfigKey
+	default:
+		return "", "", false, nil
+	}
+
+	renderer, err := c.renderer()
+	if err != nil {
+		return "", "", true, err
+	}
+
+	var content string
+	values := haproxyValues(c.getConfig())
+	if key == frontendConfigKey {
+		values.Rooms, values.Routes = c.haproxyRooms()
+		content, err = renderer.RenderFrontend(values)
+	} else {
+		content, err = renderer.RenderAuxiliary(values)
+	}
+	return key, content, true, err
+}
+
+// haproxyRooms returns the rooms of every WaitingRoom of the cluster,
+// whatever their namespace or shard. Exact paths are matched before
+// prefixes, longest prefix first.
+func (c *Controller) haproxyRooms() ([]haproxy.Room, []haproxy.Route) {
+	var rooms []haproxy.Room
+	var routes []haproxy.Route
+	for _, obj := range c.roomInformer.List() {
+		wr, ok := obj.(*wrv1alpha1.WaitingRoom)
+		if !ok {
+			c.logger.Errorf("unexpected object %v", obj)
+			continue
+		}
+		if wr.DeletionTimestamp != nil {
+			continue
+		}
+		if errs := validation.ValidateWaitingRoom(wr); len(errs) > 0 {
+			c.logger.Debugf("skipping invalid waiting room '%s/%s' in haproxy config", wr.Namespace, wr.Name)
+			continue
+		}
+		name := c.createName(wr)
+		var paths []string
+		for _, p := range wr.Spec.GetPaths() {
+			paths = append(paths, p.Path)
+			routes = append(routes, haproxy.Route{
+				Room:   name,
+				Host:   wr.Spec.Host,
+				Path:   p.Path,
+				Prefix: p.PathType == wrv1alpha1.PathTypePrefix,
+			})
+		}
+		rooms = append(rooms, haproxy.Room{
+			Name:       name,
+			CookiePath: cookiePath(paths),
+			Secure:     wr.Spec.Schema == wrv1alpha1.SchemaHTTPS,
+		})
+	}
+
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].Name < rooms[j].Name
+	})
+	sort.Slice(routes, func(i, j int) bool {
+		a, b := routes[i], routes[j]
+		if a.Prefix != b.Prefix {
+			return !a.Prefix
+		}
+		if a.Prefix && len(a.Path) != len(b.Path) {
+			return len(a.Path) > len(b.Path)
+		}
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		return a.Path < b.Path
+	})
+	return rooms, routes
+}
+
+// cookiePath returns the longest path, on segment boundaries, containing
+// all the paths of a room.
+func cookiePath(paths []string) string {
+	common := strings.Split(strings.TrimSuffix(paths[0], "/"), "/")
+	for _, p := range paths[1:] {
+		segments := strings.Split(strings.TrimSuffix(p, "/"), "/")
+		n := 0
+		for n < len(common) && n < len(segments) && common[n] == segments[n] {
+			n++
+		}
+		common = common[:n]
+	}
+	if len(common) <= 1 {
+		return "/"
+	}
+	return strings.Join(common, "/")
+}
+
+func configHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Controller) haproxyConfigDrifted(cm *corev1.ConfigMap) (bool, error) {
+	key, content, ok, err := c.desiredHAProxyConfig(cm.Name)
+	if !ok || err != nil {
+		return ok, err
+	}
+	return cm.Data[key] != content || cm.Annotations[configHashAnnotation] != configHash(content), nil
+}
+
+func (c *Controller) enqueueHAProxyConfig(name string) {
+	if !c.haproxy.ConfigWriter {
+		return
+	}
+	c.configQueue.Add(name)
+}
+
+func (c *Controller) enqueueHAProxyConfigs() {
+	for _, name := range []string{c.haproxy.ConfigMapName, c.haproxy.AuxConfigMapName} {
+		c.enqueueHAProxyConfig(name)
+	}
+}
+
+func (c *Controller) addRoom(obj interface{}) {
+	c.enqueueHAProxyConfig(c.haproxy.ConfigMapName)
+}
+
+func (c *Controller) updateRoom(oldObj, newObj interface{}) {
+	oldWr, ok := oldObj.(*wrv1alpha1.WaitingRoom)
+	if !ok {
+		c.logger.Errorf("unexpected object %v", oldObj)
+		return
+	}
+	newWr, ok := newObj.(*wrv1alpha1.WaitingRoom)
+	if !ok {
+		c.logger.Errorf("unexpected object %v", newObj)
+		return
+	}
+	if statusOnlyChange(oldWr, newWr) {
+		return
+	}
+	c.enqueueHAProxyConfig(c.haproxy.ConfigMapName)
+}
+
+func (c *Controller) deleteRoom(obj interface{}) {
+	c.enqueueHAProxyConfig(c.haproxy.ConfigMapName)
+}
+
+func (c *Controller) addConfigMap(obj interface{}) {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		c.logger.Errorf("unexpected object %v", obj)
+		return
+	}
+	if cm.Name == c.haproxy.TemplateConfigMapName {
+		c.logger.Debug("haproxy templates changed")
+		c.enqueueHAProxyConfigs()
+		return
+	}
+	drifted, err := c.haproxyConfigDrifted(cm)
+	if err != nil {
+		c.logger.Errorf("error rendering haproxy config %v", err)
+	}
+	if drifted {
+		c.logger.Debugf("haproxy configmap '%s' drifted", cm.Name)
+		c.enqueueHAProxyConfig(cm.Name)
+	}
+}
+
+func (c *Controller) updateConfigMap(oldObj, newObj interface{}) {
+	c.addConfigMap(newObj)
+}
+
+func (c *Controller) deleteConfigMap(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		c.logger.Errorf("unexpected object %v", obj)
+		return
+	}
+	if cm.Name == c.haproxy.TemplateConfigMapName {
+		c.logger.Debug("haproxy templates removed, using defaults")
+		c.enqueueHAProxyConfigs()
+	}
+}
+
+func (c *Controller) processSyncHAProxyConfig(ctx context.Context, name string) error {
+	key, content, ok, err := c.desiredHAProxyConfig(name)
+	if !ok {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error rendering haproxy config %v", err)
+	}
+
+	obj, exists, err := c.cmInformer.GetIndexer().GetByKey(c.haproxy.Namespace + "/" + name)
+	if err != nil {
+		return fmt.Errorf("error getting configmap %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("configmap '%s/%s' not found", c.haproxy.Namespace, name)
+	}
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return fmt.Errorf("unexpected object %v", obj)
+	}
+	if drifted, err := c.haproxyConfigDrifted(cm); err == nil && !drifted {
+		c.logger.Debugf("haproxy configmap '%s' up to date, skipping", name)
+		return nil
+	}
+
+	apply := corev1apply.ConfigMap(name, c.haproxy.Namespace).
+		WithAnnotations(map[string]string{configHashAnnotation: configHash(content)}).
+		WithData(map[string]string{key: content})
+
+	c.logger.Infof("applying haproxy configmap '%s/%s'", c.haproxy.Namespace, name)
+	_, err = c.kubeClientSet.CoreV1().
+		ConfigMaps(c.haproxy.Namespace).
+		Apply(ctx, apply, metav1.ApplyOptions{FieldManager: fieldManager, Force: true})
+	if err != nil {
+		return err
+	}
+
+	c.recorder.Eventf(cm, corev1.EventTypeNormal, reasonHAProxyConfigUpdated, "updated '%s'", key)
+	return nil
+}
+
+func (c *Controller) getConfig() config.Config {
+	c.configMu.RLock()
+	defer c.configMu.RUnlock()
+	return c.config
+}
+
+func (c *Controller) syncLineqConfig(ctx context.Context) {
+	lineqCfg, err := c.lineq.GetConfig(ctx)
+	c.configMu.Lock()
+	c.lineqErr = err
+	c.configMu.Unlock()
+	if err != nil {
+		c.logger.Errorf("error getting lineq config %v", err)
+		return
+	}
+	if err := lineqCfg.Validate(); err != nil {
+		c.logger.Errorf("ignoring invalid lineq config %v", err)
+		return
+	}
+	if c.setLineqConfig(lineqCfg) {
+		c.logger.Info("lineq config changed, syncing haproxy config")
+		c.enqueueHAProxyConfigs()
+	}
+}
+
+func (c *Controller) setLineqConfig(lineqCfg lineq.Config) bool {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+
+	if c.config.RoomTableName == lineqCfg.RoomTableName &&
+		c.config.UserTableName == lineqCfg.UserTableName &&
+		c.config.LineqSessionDuration == lineqCfg.SessionDuration {
+		return false
+	}
+
+	c.config.RoomTableName = lineqCfg.RoomTableName
+	c.config.UserTableName = lineqCfg.UserTableName
+	c.config.LineqSessionDuration = lineqCfg.SessionDuration
+
+	return true
+}
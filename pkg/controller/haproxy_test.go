@@ -0,0 +1,292 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hamedetemaad/lineq-operator/internal/config"
+	"github.com/hamedetemaad/lineq-operator/pkg/haproxy"
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq"
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestProcessSyncHAProxyConfig(t *testing.T) {
+	tc := newTestController(t)
+	tc.config = config.Config{
+		LineqTcpAddr:         "lineq-tcp.lineq.svc",
+		LineqTcpPort:         11111,
+		LineqHttpAddr:        "lineq-http.lineq.svc",
+		LineqHttpPort:        8060,
+		RoomTableName:        "lineq_room",
+		UserTableName:        "lineq_user",
+		LineqSessionDuration: 5,
+	}
+	ctx := context.Background()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      tc.haproxy.AuxConfigMapName,
+			Namespace: tc.haproxy.Namespace,
+		},
+		Data: map[string]string{"other": "kept"},
+	}
+	tc.addConfigMapFixture(t, cm)
+	tc.kubeClientSet.ClearActions()
+
+	if err := tc.processSyncHAProxyConfig(ctx, tc.haproxy.AuxConfigMapName); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertApplied(t, tc.kubeClientSet.Actions(), "configmaps", "")
+	assertEvents(t, tc.recorder, "Normal HAProxyConfigUpdated")
+	got := tc.getConfigMap(t, tc.haproxy.AuxConfigMapName)
+	if got.Data["other"] != "kept" {
+		t.Errorf("expected unrelated keys to be preserved, got %v", got.Data)
+	}
+	if !strings.Contains(got.Data[auxConfigKey], "expire 5m store gpc1") {
+		t.Errorf("expected rendered aux config, got %q", got.Data[auxConfigKey])
+	}
+	if got.Annotations[configHashAnnotation] != configHash(got.Data[auxConfigKey]) {
+		t.Errorf("expected hash annotation to match content, got %v", got.Annotations)
+	}
+	if tc.drifted(t, got) {
+		t.Error("expected synced configmap not to drift")
+	}
+
+	edited := got.DeepCopy()
+	edited.Data[auxConfigKey] = "hand edited"
+	if !tc.drifted(t, edited) {
+		t.Error("expected hand edited configmap to drift")
+	}
+
+	if !tc.setLineqConfig(lineq.Config{RoomTableName: "lineq_room", UserTableName: "lineq_user", SessionDuration: 10}) {
+		t.Fatal("expected lineq config change to be detected")
+	}
+	if !tc.drifted(t, got) {
+		t.Error("expected configmap to drift after lineq config change")
+	}
+}
+
+func TestSyncLineqConfigIgnoresInvalid(t *testing.T) {
+	tc := newTestController(t)
+	valid := config.Config{RoomTableName: "lineq_room", UserTableName: "lineq_user", LineqSessionDuration: 5}
+	tc.config = valid
+
+	tc.lineqServer.SetConfig(lineq.Config{RoomTableName: "", UserTableName: "lineq_user", SessionDuration: 0})
+	tc.syncLineqConfig(context.Background())
+
+	if got := tc.getConfig(); got.RoomTableName != valid.RoomTableName || got.LineqSessionDuration != valid.LineqSessionDuration {
+		t.Errorf("expected invalid lineq config to be ignored, got %v", got)
+	}
+	if tc.configQueue.Len() != 0 {
+		t.Errorf("expected no haproxy sync for an invalid config, got %d items", tc.configQueue.Len())
+	}
+}
+
+func TestHAProxyRoomsAllNamespaces(t *testing.T) {
+	tenantA := newWaitingRoom("shop", "tenant-a")
+	tenantB := newWaitingRoom("blog", "tenant-b")
+	tenantB.Spec.Path = "/blog"
+	tc := newScopedTestController(t, []string{"tenant-a"}, "", tenantA, tenantB)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	tc.wrInformer.Run(stopCh)
+	tc.roomInformer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, tc.wrInformer.HasSynced, tc.roomInformer.HasSynced) {
+		t.Fatal("informer caches did not sync")
+	}
+
+	if objs := tc.wrInformer.List(); len(objs) != 1 {
+		t.Errorf("expected only the rooms of the watched namespace to be reconciled, got %d", len(objs))
+	}
+	rooms, _ := tc.haproxyRooms()
+	wantRooms := []haproxy.Room{
+		{Name: "example_com_blog", CookiePath: "/blog"},
+		{Name: "example_com_checkout", CookiePath: "/checkout"},
+	}
+	if !reflect.DeepEqual(rooms, wantRooms) {
+		t.Errorf("expected rooms %+v, got %+v", wantRooms, rooms)
+	}
+}
+
+func TestHAProxyConfigWriterDisabled(t *testing.T) {
+	tc := newTestController(t)
+	tc.haproxy.ConfigWriter = false
+
+	tc.addRoom(newWaitingRoom("shop", "test"))
+	tc.setLineqConfig(lineq.Config{RoomTableName: "lineq_room", UserTableName: "lineq_user", SessionDuration: 5})
+	tc.lineqServer.SetConfig(lineq.Config{RoomTableName: "lineq_room", UserTableName: "lineq_user", SessionDuration: 10})
+	tc.syncLineqConfig(context.Background())
+
+	if tc.configQueue.Len() != 0 {
+		t.Errorf("expected no haproxy sync on a non writer, got %d items", tc.configQueue.Len())
+	}
+}
+
+func TestProcessSyncHAProxyConfigTemplateOverride(t *testing.T) {
+	tc := newTestController(t)
+	tc.haproxy.TemplateConfigMapName = "lineq-templates"
+	tc.config = config.Config{RoomTableName: "lineq_room", UserTableName: "lineq_user", LineqSessionDuration: 5}
+	ctx := context.Background()
+
+	tc.addConfigMapFixture(t, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: tc.haproxy.AuxConfigMapName, Namespace: tc.haproxy.Namespace},
+	})
+	tc.addConfigMapFixture(t, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "lineq-templates", Namespace: tc.haproxy.Namespace},
+		Data: map[string]string{
+			haproxy.AuxiliaryTemplateKey: "backend {{ .UserTableName }} expire {{ .LineqSessionDuration }}m\n",
+		},
+	})
+
+	if err := tc.processSyncHAProxyConfig(ctx, tc.haproxy.AuxConfigMapName); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := tc.getConfigMap(t, tc.haproxy.AuxConfigMapName)
+	if want := "backend lineq_user expire 5m\n"; got.Data[auxConfigKey] != want {
+		t.Errorf("expected %q, got %q", want, got.Data[auxConfigKey])
+	}
+}
+
+func TestHAProxyRooms(t *testing.T) {
+	shop := newWaitingRoom("shop", "test")
+	shop.Spec.Path = ""
+	shop.Spec.Paths = []wrv1alpha1.WaitingRoomPath{
+		{Path: "/shop/checkout"},
+		{Path: "/shop/cart", PathType: wrv1alpha1.PathTypePrefix},
+	}
+	otherShard := newWaitingRoom("blog", "test")
+	otherShard.Labels = map[string]string{"lineq.io/shard": "b"}
+	otherShard.Spec.Path = ""
+	otherShard.Spec.Paths = []wrv1alpha1.WaitingRoomPath{{Path: "/blog", PathType: wrv1alpha1.PathTypePrefix}}
+	deleting := newWaitingRoom("news", "test")
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+	deleting.Spec.Path = "/news"
+	unsafe := newWaitingRoom("unsafe", "test")
+	unsafe.Spec.Path = "/a b"
+
+	tc := newShardedTestController(t, "lineq.io/shard=a")
+	for _, wr := range []*wrv1alpha1.WaitingRoom{shop, otherShard, deleting, unsafe} {
+		if err := tc.roomInformer.Indexer("test").Add(wr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rooms, routes := tc.haproxyRooms()
+
+	wantRooms := []haproxy.Room{
+		{Name: "example_com_blog", CookiePath: "/blog"},
+		{Name: "example_com_shop_cart", CookiePath: "/shop"},
+	}
+	if !reflect.DeepEqual(rooms, wantRooms) {
+		t.Errorf("expected rooms %+v, got %+v", wantRooms, rooms)
+	}
+	wantRoutes := []haproxy.Route{
+		{Room: "example_com_shop_cart", Host: "example.com", Path: "/shop/checkout"},
+		{Room: "example_com_shop_cart", Host: "example.com", Path: "/shop/cart", Prefix: true},
+		{Room: "example_com_blog", Host: "example.com", Path: "/blog", Prefix: true},
+	}
+	if !reflect.DeepEqual(routes, wantRoutes) {
+		t.Errorf("expected routes %+v, got %+v", wantRoutes, routes)
+	}
+}
+
+func TestCookiePath(t *testing.T) {
+	for _, tt := range []struct {
+		paths []string
+		want  string
+	}{
+		{[]string{"/"}, "/"},
+		{[]string{"/checkout"}, "/checkout"},
+		{[]string{"/shop/"}, "/shop"},
+		{[]string{"/shop/cart", "/shop/checkout"}, "/shop"},
+		{[]string{"/shop", "/shopping"}, "/"},
+		{[]string{"/cart", "/checkout"}, "/"},
+	} {
+		if got := cookiePath(tt.paths); got != tt.want {
+			t.Errorf("cookiePath(%v): expected '%s', got '%s'", tt.paths, tt.want, got)
+		}
+	}
+}
+
+func TestRendererCachedUntilTemplatesChange(t *testing.T) {
+	tc := newTestController(t)
+	tc.haproxy.TemplateConfigMapName = "lineq-templates"
+	templates := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "lineq-templates", Namespace: tc.haproxy.Namespace},
+		Data:       map[string]string{haproxy.AuxiliaryTemplateKey: "backend {{ .UserTableName }}\n"},
+	}
+	tc.addConfigMapFixture(t, templates)
+
+	first, err := tc.renderer()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := tc.renderer()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first != second {
+		t.Error("expected renderer to be reused while the templates are unchanged")
+	}
+
+	changed := templates.DeepCopy()
+	changed.Data[haproxy.AuxiliaryTemplateKey] = "backend {{ .RoomTableName }}\n"
+	if err := tc.cmInformer.GetIndexer().Update(changed); err != nil {
+		t.Fatal(err)
+	}
+	third, err := tc.renderer()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if third == second {
+		t.Error("expected renderer to be rebuilt after the templates changed")
+	}
+}
+
+func TestProcessSyncHAProxyConfigMissing(t *testing.T) {
+	tc := newTestController(t)
+
+	if err := tc.processSyncHAProxyConfig(context.Background(), tc.haproxy.ConfigMapName); err == nil {
+		t.Fatal("expected error for missing configmap")
+	}
+}
+
+func (tc *testController) drifted(t *testing.T, cm *corev1.ConfigMap) bool {
+	t.Helper()
+	drifted, err := tc.haproxyConfigDrifted(cm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return drifted
+}
+
+func (tc *testController) addConfigMapFixture(t *testing.T, cm *corev1.ConfigMap) {
+	t.Helper()
+	if _, err := tc.kubeClientSet.CoreV1().
+		ConfigMaps(cm.Namespace).
+		Create(context.Background(), cm, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("error creating configmap: %v", err)
+	}
+	if err := tc.cmInformer.GetIndexer().Add(cm); err != nil {
+		t.Fatalf("error adding configmap to indexer: %v", err)
+	}
+}
+
+func (tc *testController) getConfigMap(t *testing.T, name string) *corev1.ConfigMap {
+	t.Helper()
+	cm, err := tc.kubeClientSet.CoreV1().
+		ConfigMaps(tc.haproxy.Namespace).
+		Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting configmap: %v", err)
+	}
+	return cm
+}
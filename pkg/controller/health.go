@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// HasSynced reports whether the informer caches have synced, which only
+// happens once Run has started. The HAProxy caches only run on the config
+// writer.
+func (c *Controller) HasSynced() bool {
+	if !c.wrInformer.HasSynced() ||
+		!c.ingInformer.HasSynced() ||
+		!c.svcInformer.HasSynced() {
+		return false
+	}
+	if !c.haproxy.ConfigWriter {
+		return true
+	}
+	return c.roomInformer.HasSynced() && c.cmInformer.HasSynced()
+}
+
+// CheckLineqConfig returns an error until the LineQ table names the HAProxy
+// config is rendered from are known. A failed periodic config sync is only
+// reported in the message, the last known config keeps being served and the
+// webhook in the same pod must stay reachable. Probes don't call LineQ
+// themselves.
+func (c *Controller) CheckLineqConfig() (string, error) {
+	c.configMu.RLock()
+	cfg, lineqErr := c.config, c.lineqErr
+	c.configMu.RUnlock()
+	if cfg.RoomTableName == "" || cfg.UserTableName == "" {
+		return "", errors.New("lineq config not loaded")
+	}
+	if lineqErr != nil {
+		return fmt.Sprintf("lineq unreachable, using the last known config: %v", lineqErr), nil
+	}
+	return "", nil
+}
+
+// CheckHAProxyConfig returns an error while the HAProxy configmaps are
+// missing or don't hold the rendered config yet.
+func (c *Controller) CheckHAProxyConfig() error {
+	for _, name := range []string{c.haproxy.ConfigMapName, c.haproxy.AuxConfigMapName} {
+		obj, exists, err := c.cmInformer.GetIndexer().GetByKey(c.haproxy.Namespace + "/" + name)
+		if err != nil {
+			return fmt.Errorf("error getting configmap '%s/%s' %v", c.haproxy.Namespace, name, err)
+		}
+		if !exists {
+			return fmt.Errorf("configmap '%s/%s' not found", c.haproxy.Namespace, name)
+		}
+		cm, ok := obj.(*corev1.ConfigMap)
+		if !ok {
+			return fmt.Errorf("unexpected object %v", obj)
+		}
+		drifted, err := c.haproxyConfigDrifted(cm)
+		if err != nil {
+			return fmt.Errorf("error rendering haproxy config %v", err)
+		}
+		if drifted {
+			return fmt.Errorf("configmap '%s/%s' not synced", c.haproxy.Namespace, name)
+		}
+	}
+	return nil
+}
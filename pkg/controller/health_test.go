@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hamedetemaad/lineq-operator/internal/config"
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckLineqConfig(t *testing.T) {
+	tc := newTestController(t)
+	if _, err := tc.CheckLineqConfig(); err == nil {
+		t.Error("expected error before the lineq config is loaded")
+	}
+
+	tc.config = config.Config{RoomTableName: "lineq_room", UserTableName: "lineq_user"}
+	if message, err := tc.CheckLineqConfig(); err != nil || message != "" {
+		t.Errorf("unexpected result %q, %v", message, err)
+	}
+
+	// A LineQ outage doesn't fail readiness, which would take the webhook
+	// served by the same pod down with it.
+	tc.lineqServer.FailNext("getConfig", http.StatusServiceUnavailable)
+	tc.syncLineqConfig(context.Background())
+	message, err := tc.CheckLineqConfig()
+	if err != nil {
+		t.Errorf("unexpected error after a failed lineq config sync: %v", err)
+	}
+	if !strings.HasPrefix(message, "lineq unreachable") {
+		t.Errorf("expected the failed sync to be reported, got %q", message)
+	}
+
+	tc.lineqServer.SetConfig(lineq.Config{RoomTableName: "lineq_room", UserTableName: "lineq_user", SessionDuration: 5})
+	tc.syncLineqConfig(context.Background())
+	if message, err := tc.CheckLineqConfig(); err != nil || message != "" {
+		t.Errorf("unexpected result after a successful sync %q, %v", message, err)
+	}
+}
+
+func TestCheckHAProxyConfig(t *testing.T) {
+	tc := newTestController(t)
+	tc.config = config.Config{
+		RoomTableName:        "lineq_room",
+		UserTableName:        "lineq_user",
+		LineqSessionDuration: 5,
+	}
+	if err := tc.CheckHAProxyConfig(); err == nil {
+		t.Error("expected error for missing configmaps")
+	}
+
+	for _, name := range []string{tc.haproxy.ConfigMapName, tc.haproxy.AuxConfigMapName} {
+		tc.addConfigMapFixture(t, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: tc.haproxy.Namespace},
+		})
+	}
+	if err := tc.CheckHAProxyConfig(); err == nil {
+		t.Error("expected error for configmaps not synced")
+	}
+
+	for _, name := range []string{tc.haproxy.ConfigMapName, tc.haproxy.AuxConfigMapName} {
+		if err := tc.processSyncHAProxyConfig(context.Background(), name); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := tc.cmInformer.GetIndexer().Update(tc.getConfigMap(t, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tc.CheckHAProxyConfig(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	wr "github.com/hamedetemaad/lineq-operator/pkg/waitingroom"
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	netv1apply "k8s.io/client-go/applyconfigurations/networking/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func (c *Controller) applyIngress(ctx context.Context, ing *netv1apply.IngressApplyConfiguration) error {
+	_, err := c.kubeClientSet.NetworkingV1().
+		Ingresses(*ing.Namespace).
+		Apply(ctx, ing, metav1.ApplyOptions{FieldManager: fieldManager, Force: true})
+	if err != nil {
+		return fmt.Errorf("error applying ingress %v", err)
+	}
+	return nil
+}
+
+func (c *Controller) updateIngress(oldObj, newObj interface{}) {
+	oldIng, ok := oldObj.(*netv1.Ingress)
+	if !ok {
+		c.logger.Errorf("unexpected object %v", oldObj)
+		return
+	}
+	newIng, ok := newObj.(*netv1.Ingress)
+	if !ok {
+		c.logger.Errorf("unexpected object %v", newObj)
+		return
+	}
+	if reflect.DeepEqual(oldIng.Spec, newIng.Spec) {
+		return
+	}
+	c.enqueueIngressOwner(newIng, "ingress changed")
+}
+
+func (c *Controller) deleteIngress(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	ing, ok := obj.(*netv1.Ingress)
+	if !ok {
+		c.logger.Errorf("unexpected object %v", obj)
+		return
+	}
+	c.enqueueIngressOwner(ing, "ingress deleted")
+}
+
+// enqueueIngressOwner syncs the WaitingRoom controlling ing, so that manual
+// edits or deletions of the generated Ingress get reverted.
+func (c *Controller) enqueueIngressOwner(ing *netv1.Ingress, reason string) {
+	ref := metav1.GetControllerOf(ing)
+	if ref == nil || ref.Kind != wr.WaitingRoomKind || ref.APIVersion != wrv1alpha1.SchemeGroupVersion.String() {
+		return
+	}
+	key := ing.Namespace + "/" + ref.Name
+	obj, exists, err := c.wrInformer.GetByKey(key)
+	if err != nil {
+		c.logger.Errorf("error getting waiting room '%s' %v", key, err)
+		return
+	}
+	if !exists {
+		return
+	}
+	if owner, ok := obj.(*wrv1alpha1.WaitingRoom); !ok || owner.UID != ref.UID {
+		return
+	}
+	c.logger.Debugf("%s, syncing waiting room '%s'", reason, key)
+	c.queue.Add(key)
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIngressDriftRepaired(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	tc := newTestController(t, room)
+	ctx := context.Background()
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ing, err := tc.kubeClientSet.NetworkingV1().Ingresses("test").Get(ctx, "shop", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting ingress: %v", err)
+	}
+
+	edited := ing.DeepCopy()
+	edited.Spec.Rules[0].Host = "other.example.com"
+	if _, err := tc.kubeClientSet.NetworkingV1().Ingresses("test").Update(ctx, edited, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	tc.updateIngress(ing, edited)
+	if tc.queue.Len() != 1 {
+		t.Fatalf("expected waiting room to be queued, got %d items", tc.queue.Len())
+	}
+	tc.processQueue(t)
+
+	ing, err = tc.kubeClientSet.NetworkingV1().Ingresses("test").Get(ctx, "shop", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting ingress: %v", err)
+	}
+	assertIngress(t, ing, "example.com.vwr", "/checkout", "shop", 80)
+
+	if err := tc.kubeClientSet.NetworkingV1().Ingresses("test").Delete(ctx, "shop", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	tc.deleteIngress(ing)
+	tc.processQueue(t)
+
+	ing, err = tc.kubeClientSet.NetworkingV1().Ingresses("test").Get(ctx, "shop", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected deleted ingress to be recreated: %v", err)
+	}
+	assertIngress(t, ing, "example.com.vwr", "/checkout", "shop", 80)
+}
+
+func TestIngressWithoutOwnerIgnored(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	tc := newTestController(t, room)
+	if err := tc.wrInformer.Indexer("test").Add(room); err != nil {
+		t.Fatal(err)
+	}
+
+	ing := &netv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "test"},
+	}
+	edited := ing.DeepCopy()
+	edited.Spec.Rules = []netv1.IngressRule{{Host: "example.com"}}
+	tc.updateIngress(ing, edited)
+	tc.deleteIngress(ing)
+
+	if tc.queue.Len() != 0 {
+		t.Errorf("expected unowned ingress to be ignored, got %d items", tc.queue.Len())
+	}
+}
@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const metricsNamespace = "lineq_operator"
+
+const (
+	syncResultSuccess = "success"
+	syncResultRetry   = "retry"
+	syncResultDropped = "dropped"
+)
+
+var (
+	informerEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "informer_events_total",
+		Help:      "Informer events handled by the controller, by resource and event type.",
+	}, []string{"resource", "event"})
+
+	syncTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "sync_total",
+		Help:      "Processed queue items, by queue and result.",
+	}, []string{"queue", "result"})
+
+	syncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "sync_duration_seconds",
+		Help:      "Time spent processing a queue item, by queue.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"queue"})
+
+	managedWaitingRooms = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "waiting_rooms",
+		Help:      "WaitingRooms managed by this operator instance.",
+	})
+)
+
+var (
+	workqueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "depth",
+		Help:      "Current depth of the workqueue.",
+	}, []string{"name"})
+
+	workqueueAdds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "adds_total",
+		Help:      "Items added to the workqueue.",
+	}, []string{"name"})
+
+	workqueueLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "queue_duration_seconds",
+		Help:      "Time an item stays in the workqueue before being processed.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+	}, []string{"name"})
+
+	workqueueWorkDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "work_duration_seconds",
+		Help:      "Time spent processing an item from the workqueue.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+	}, []string{"name"})
+
+	workqueueUnfinishedWork = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "unfinished_work_seconds",
+		Help:      "Seconds of work in progress that hasn't been observed by work_duration_seconds yet.",
+	}, []string{"name"})
+
+	workqueueLongestRunning = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "longest_running_processor_seconds",
+		Help:      "Seconds the longest running processor has been running.",
+	}, []string{"name"})
+
+	workqueueRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "retries_total",
+		Help:      "Rate limited retries of workqueue items.",
+	}, []string{"name"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		informerEvents,
+		syncTotal,
+		syncDuration,
+		managedWaitingRooms,
+		workqueueDepth,
+		workqueueAdds,
+		workqueueLatency,
+		workqueueWorkDuration,
+		workqueueUnfinishedWork,
+		workqueueLongestRunning,
+		workqueueRetries,
+	)
+	workqueue.SetProvider(workqueueMetricsProvider{})
+}
+
+// workqueueMetricsProvider exposes the metrics of the named workqueues.
+type workqueueMetricsProvider struct{}
+
+func (workqueueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return workqueueDepth.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return workqueueAdds.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return workqueueLatency.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return workqueueWorkDuration.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return workqueueUnfinishedWork.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return workqueueLongestRunning.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return workqueueRetries.WithLabelValues(name)
+}
+
+func observeSync(queue, result string, start time.Time) {
+	syncTotal.WithLabelValues(queue, result).Inc()
+	syncDuration.WithLabelValues(queue).Observe(time.Since(start).Seconds())
+}
+
+// countEvents wraps the handlers to count the informer events they receive.
+func countEvents(resource string, handler cache.ResourceEventHandlerFuncs) cache.ResourceEventHandlerFuncs {
+	counted := cache.ResourceEventHandlerFuncs{}
+	if handler.AddFunc != nil {
+		counted.AddFunc = func(obj interface{}) {
+			informerEvents.WithLabelValues(resource, "add").Inc()
+			handler.AddFunc(obj)
+		}
+	}
+	if handler.UpdateFunc != nil {
+		counted.UpdateFunc = func(oldObj, newObj interface{}) {
+			informerEvents.WithLabelValues(resource, "update").Inc()
+			handler.UpdateFunc(oldObj, newObj)
+		}
+	}
+	if handler.DeleteFunc != nil {
+		counted.DeleteFunc = func(obj interface{}) {
+			informerEvents.WithLabelValues(resource, "delete").Inc()
+			handler.DeleteFunc(obj)
+		}
+	}
+	return counted
+}
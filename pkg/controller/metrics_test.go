@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestSyncMetrics(t *testing.T) {
+	tc := newTestController(t)
+	ctx := context.Background()
+	success := func(ctx context.Context, key string) error { return nil }
+	failing := func(ctx context.Context, key string) error { return errors.New("lineq unavailable") }
+
+	count := func(result string) float64 {
+		return testutil.ToFloat64(syncTotal.WithLabelValues(waitingRoomQueueName, result))
+	}
+	before := map[string]float64{}
+	for _, result := range []string{syncResultSuccess, syncResultRetry, syncResultDropped} {
+		before[result] = count(result)
+	}
+
+	tc.queue.Add("test/shop")
+	tc.processNextItem(ctx, waitingRoomQueueName, tc.queue, success, tc.waitingRoomObject)
+	tc.queue.Add("test/blog")
+	for i := 0; i <= maxRetries; i++ {
+		tc.processNextItem(ctx, waitingRoomQueueName, tc.queue, failing, tc.waitingRoomObject)
+	}
+
+	for result, want := range map[string]float64{
+		syncResultSuccess: 1,
+		syncResultRetry:   maxRetries,
+		syncResultDropped: 1,
+	} {
+		if got := count(result) - before[result]; got != want {
+			t.Errorf("expected %v '%s' syncs, got %v", want, result, got)
+		}
+	}
+}
+
+func TestCountEvents(t *testing.T) {
+	var added, deleted int
+	handler := countEvents("test", cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { added++ },
+		DeleteFunc: func(obj interface{}) { deleted++ },
+	})
+	before := testutil.ToFloat64(informerEvents.WithLabelValues("test", "add"))
+
+	handler.OnAdd(nil, false)
+	handler.OnUpdate(nil, nil)
+	handler.OnDelete(nil)
+
+	if added != 1 || deleted != 1 {
+		t.Errorf("expected wrapped handlers to be called once, got add=%d delete=%d", added, deleted)
+	}
+	if got := testutil.ToFloat64(informerEvents.WithLabelValues("test", "add")) - before; got != 1 {
+		t.Errorf("expected 1 add event, got %v", got)
+	}
+}
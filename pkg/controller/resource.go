@@ -4,54 +4,62 @@ import (
 	wr "github.com/hamedetemaad/lineq-operator/pkg/waitingroom"
 	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
 	netv1 "k8s.io/api/networking/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1apply "k8s.io/client-go/applyconfigurations/meta/v1"
+	netv1apply "k8s.io/client-go/applyconfigurations/networking/v1"
 )
 
-func createIngress(newWaitingRoom *wrv1alpha1.WaitingRoom, namespace string) *netv1.Ingress {
-	return &netv1.Ingress{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      newWaitingRoom.ObjectMeta.Name,
-			Namespace: namespace,
-			Labels:    make(map[string]string),
-			OwnerReferences: []metav1.OwnerReference{
-				*metav1.NewControllerRef(
-					newWaitingRoom,
-					wrv1alpha1.SchemeGroupVersion.WithKind(wr.WaitingRoomKind),
-				),
-			},
-		},
-		Spec: createIngressSpec(newWaitingRoom.Name, namespace, newWaitingRoom.Spec.Path, newWaitingRoom.Spec.ActiveUsers, newWaitingRoom.Spec.Host, newWaitingRoom.Spec.Schema, newWaitingRoom.Spec.BackendSvcAddr, newWaitingRoom.Spec.BackendSvcPort),
-	}
+func createIngress(newWaitingRoom *wrv1alpha1.WaitingRoom, namespace string, backend netv1.IngressServiceBackend) *netv1apply.IngressApplyConfiguration {
+	gvk := wrv1alpha1.SchemeGroupVersion.WithKind(wr.WaitingRoomKind)
+	return netv1apply.Ingress(newWaitingRoom.Name, namespace).
+		WithOwnerReferences(
+			metav1apply.OwnerReference().
+				WithAPIVersion(gvk.GroupVersion().String()).
+				WithKind(gvk.Kind).
+				WithName(newWaitingRoom.Name).
+				WithUID(newWaitingRoom.UID).
+				WithController(true).
+				WithBlockOwnerDeletion(true),
+		).
+		WithSpec(createIngressSpec(newWaitingRoom.Spec.GetPaths(), newWaitingRoom.Spec.Host, newWaitingRoom.Spec.Schema, newWaitingRoom.Spec.TLSSecretName, backend))
 }
 
-func createIngressSpec(name, namespace, path string, activeUsers int, host string, scheme string, backSvcAddr string, backSvcPort int) netv1.IngressSpec {
-	pathTypeExact := netv1.PathTypeExact
-	ingressClassName := "haproxy"
-	ingressRule := netv1.IngressRule{
-		Host: host + ".vwr",
-		IngressRuleValue: netv1.IngressRuleValue{
-			HTTP: &netv1.HTTPIngressRuleValue{
-				Paths: []netv1.HTTPIngressPath{
-					{
-						Path:     path,
-						PathType: &pathTypeExact,
-						Backend: netv1.IngressBackend{
-							Service: &netv1.IngressServiceBackend{
-								Name: backSvcAddr,
-								Port: netv1.ServiceBackendPort{
-									Number: int32(backSvcPort),
-								},
-							},
-						},
-					},
-				},
-			},
-		},
+func createIngressSpec(paths []wrv1alpha1.WaitingRoomPath, host string, scheme string, tlsSecretName string, backend netv1.IngressServiceBackend) *netv1apply.IngressSpecApplyConfiguration {
+	port := netv1apply.ServiceBackendPort()
+	if backend.Port.Name != "" {
+		port.WithName(backend.Port.Name)
+	} else {
+		port.WithNumber(backend.Port.Number)
+	}
+
+	httpRule := netv1apply.HTTPIngressRuleValue()
+	for _, p := range paths {
+		pathType := netv1.PathTypeExact
+		if p.PathType == wrv1alpha1.PathTypePrefix {
+			pathType = netv1.PathTypePrefix
+		}
+		httpRule.WithPaths(netv1apply.HTTPIngressPath().
+			WithPath(p.Path).
+			WithPathType(pathType).
+			WithBackend(netv1apply.IngressBackend().
+				WithService(netv1apply.IngressServiceBackend().
+					WithName(backend.Name).
+					WithPort(port),
+				),
+			),
+		)
 	}
 
-	ingressSpec := netv1.IngressSpec{
-		IngressClassName: &ingressClassName,
-		Rules:            []netv1.IngressRule{ingressRule},
+	ingressSpec := netv1apply.IngressSpec().
+		WithIngressClassName("haproxy").
+		WithRules(netv1apply.IngressRule().
+			WithHost(host + ".vwr").
+			WithHTTP(httpRule),
+		)
+	if scheme == wrv1alpha1.SchemaHTTPS {
+		ingressSpec.WithTLS(netv1apply.IngressTLS().
+			WithHosts(host).
+			WithSecretName(tlsSecretName),
+		)
 	}
 	return ingressSpec
 }
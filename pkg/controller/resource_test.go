@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"testing"
+
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	netv1 "k8s.io/api/networking/v1"
+)
+
+func TestCreateIngressTLS(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	backend := netv1.IngressServiceBackend{
+		Name: "shop",
+		Port: netv1.ServiceBackendPort{Number: 80},
+	}
+
+	if ing := createIngress(room, room.Namespace, backend); len(ing.Spec.TLS) != 0 {
+		t.Errorf("expected no tls for http rooms, got %v", ing.Spec.TLS)
+	}
+
+	room.Spec.Schema = wrv1alpha1.SchemaHTTPS
+	room.Spec.TLSSecretName = "example-com-tls"
+	ing := createIngress(room, room.Namespace, backend)
+	if len(ing.Spec.TLS) != 1 {
+		t.Fatalf("expected 1 tls entry, got %v", ing.Spec.TLS)
+	}
+	tls := ing.Spec.TLS[0]
+	if *tls.SecretName != "example-com-tls" {
+		t.Errorf("expected secret 'example-com-tls', got '%s'", *tls.SecretName)
+	}
+	if len(tls.Hosts) != 1 || tls.Hosts[0] != room.Spec.Host {
+		t.Errorf("expected tls hosts [%s], got %v", room.Spec.Host, tls.Hosts)
+	}
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"strings"
+
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq"
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/tools/cache"
+)
+
+var roomLabels = []string{"namespace", "name", "host", "path"}
+
+var (
+	roomActiveUsers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "lineq",
+		Subsystem: "room",
+		Name:      "active_users",
+		Help:      "Users admitted to the waiting room, as reported by LineQ.",
+	}, roomLabels)
+
+	roomWaitingUsers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "lineq",
+		Subsystem: "room",
+		Name:      "waiting_users",
+		Help:      "Users queued in the waiting room, as reported by LineQ.",
+	}, roomLabels)
+
+	roomCapacity = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "lineq",
+		Subsystem: "room",
+		Name:      "capacity",
+		Help:      "Maximum number of active users, from spec.activeUsers.",
+	}, roomLabels)
+
+	roomAdmittedUsers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "lineq",
+		Subsystem: "room",
+		Name:      "admitted_users_total",
+		Help:      "Users admitted to the waiting room, as reported by LineQ.",
+	}, roomLabels)
+)
+
+func init() {
+	prometheus.MustRegister(roomActiveUsers, roomWaitingUsers, roomCapacity, roomAdmittedUsers)
+}
+
+func roomMetricLabels(wr *wrv1alpha1.WaitingRoom) []string {
+	var paths []string
+	for _, p := range wr.Spec.GetPaths() {
+		paths = append(paths, p.Path)
+	}
+	return []string{wr.Namespace, wr.Name, wr.Spec.Host, strings.Join(paths, ",")}
+}
+
+// setRoomMetrics publishes the series of a room. Stats are nil when LineQ
+// couldn't report them, the user counts are dropped then rather than left at
+// stale values.
+func (c *Controller) setRoomMetrics(wr *wrv1alpha1.WaitingRoom, stats *lineq.RoomStats) {
+	key, err := cache.MetaNamespaceKeyFunc(wr)
+	if err != nil {
+		c.logger.Errorf("error getting key %v", err)
+		return
+	}
+	labels := roomMetricLabels(wr)
+	if old, ok := c.roomSeries[key]; ok && strings.Join(old, "\x00") != strings.Join(labels, "\x00") {
+		deleteRoomMetrics(old)
+		delete(c.roomAdmitted, key)
+	}
+	c.roomSeries[key] = labels
+
+	roomCapacity.WithLabelValues(labels...).Set(float64(wr.Spec.ActiveUsers))
+	if stats == nil {
+		roomActiveUsers.DeleteLabelValues(labels...)
+		roomWaitingUsers.DeleteLabelValues(labels...)
+		return
+	}
+	roomActiveUsers.WithLabelValues(labels...).Set(float64(stats.ActiveUsers))
+	roomWaitingUsers.WithLabelValues(labels...).Set(float64(stats.WaitingUsers))
+	if stats.AdmittedUsers != nil {
+		roomAdmittedUsers.WithLabelValues(labels...).Add(float64(c.admittedSince(key, *stats.AdmittedUsers)))
+	}
+}
+
+// admittedSince returns the users admitted to a room since its last sync. A
+// total lower than the last one means LineQ lost its counts, the new total
+// is then the count since that reset.
+func (c *Controller) admittedSince(key string, total int64) int64 {
+	last, ok := c.roomAdmitted[key]
+	c.roomAdmitted[key] = total
+	if !ok || total < last {
+		return total
+	}
+	return total - last
+}
+
+// pruneRoomMetrics drops the series of rooms that are no longer managed.
+func (c *Controller) pruneRoomMetrics(managed map[string]bool) {
+	for key, labels := range c.roomSeries {
+		if !managed[key] {
+			deleteRoomMetrics(labels)
+			delete(c.roomSeries, key)
+			delete(c.roomAdmitted, key)
+		}
+	}
+}
+
+func deleteRoomMetrics(labels []string) {
+	for _, gauge := range []*prometheus.GaugeVec{roomActiveUsers, roomWaitingUsers, roomCapacity} {
+		gauge.DeleteLabelValues(labels...)
+	}
+	roomAdmittedUsers.DeleteLabelValues(labels...)
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq"
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSyncStatsRoomMetrics(t *testing.T) {
+	shop := newWaitingRoom("shop", "metrics")
+	blog := newWaitingRoom("blog", "metrics")
+	blog.Spec.Host = "blog.example.com"
+	blog.Spec.Path = ""
+	blog.Spec.Paths = []wrv1alpha1.WaitingRoomPath{{Path: "/posts"}, {Path: "/feed"}}
+	tc := newTestController(t, shop, blog)
+	for _, room := range []*wrv1alpha1.WaitingRoom{shop, blog} {
+		if err := tc.wrInformer.Indexer("metrics").Add(room); err != nil {
+			t.Fatal(err)
+		}
+		tc.lineqServer.AddRoom(tc.createRoom(room, room.Spec.RoomName()))
+	}
+	tc.lineqServer.SetStats(shop.Spec.RoomName(), lineq.RoomStats{ActiveUsers: 15, WaitingUsers: 40})
+	tc.lineqServer.SetStats(blog.Spec.RoomName(), lineq.RoomStats{ActiveUsers: 3, WaitingUsers: 0})
+	ctx := context.Background()
+
+	tc.syncStats(ctx)
+
+	shopLabels := []string{"metrics", "shop", "example.com", "/checkout"}
+	blogLabels := []string{"metrics", "blog", "blog.example.com", "/posts,/feed"}
+	for _, tt := range []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"shop active users", testutil.ToFloat64(roomActiveUsers.WithLabelValues(shopLabels...)), 15},
+		{"shop waiting users", testutil.ToFloat64(roomWaitingUsers.WithLabelValues(shopLabels...)), 40},
+		{"shop capacity", testutil.ToFloat64(roomCapacity.WithLabelValues(shopLabels...)), 20},
+		{"blog active users", testutil.ToFloat64(roomActiveUsers.WithLabelValues(blogLabels...)), 3},
+		{"managed rooms", testutil.ToFloat64(managedWaitingRooms), 2},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("expected %s %v, got %v", tt.name, tt.want, tt.got)
+		}
+	}
+
+	if err := tc.wrInformer.Indexer("metrics").Delete(blog); err != nil {
+		t.Fatal(err)
+	}
+	tc.syncStats(ctx)
+
+	if _, ok := tc.roomSeries["metrics/blog"]; ok {
+		t.Error("expected series of deleted room to be dropped")
+	}
+	if roomActiveUsers.DeleteLabelValues(blogLabels...) {
+		t.Error("expected active users series of deleted room to be removed")
+	}
+	if got := testutil.ToFloat64(managedWaitingRooms); got != 1 {
+		t.Errorf("expected 1 managed room, got %v", got)
+	}
+
+	tc.lineqServer.FailNext("getStats", http.StatusServiceUnavailable)
+	tc.syncStats(ctx)
+
+	if roomActiveUsers.DeleteLabelValues(shopLabels...) || roomWaitingUsers.DeleteLabelValues(shopLabels...) {
+		t.Error("expected user series to be removed when LineQ fails")
+	}
+	if got := testutil.ToFloat64(roomCapacity.WithLabelValues(shopLabels...)); got != 20 {
+		t.Errorf("expected shop capacity 20, got %v", got)
+	}
+}
+
+func TestSyncStatsAdmittedUsers(t *testing.T) {
+	shop := newWaitingRoom("shop", "admitted")
+	tc := newTestController(t, shop)
+	if err := tc.wrInformer.Indexer("admitted").Add(shop); err != nil {
+		t.Fatal(err)
+	}
+	tc.lineqServer.AddRoom(tc.createRoom(shop, shop.Spec.RoomName()))
+	shopLabels := []string{"admitted", "shop", "example.com", "/checkout"}
+	ctx := context.Background()
+
+	tc.lineqServer.SetStats(shop.Spec.RoomName(), lineq.RoomStats{ActiveUsers: 15})
+	tc.syncStats(ctx)
+	if roomAdmittedUsers.DeleteLabelValues(shopLabels...) {
+		t.Fatal("expected no admitted users series when LineQ doesn't report them")
+	}
+
+	for _, tt := range []struct {
+		total int64
+		want  float64
+	}{
+		{100, 100},
+		{130, 130},
+		{130, 130},
+		// LineQ restarted and counts from 0 again.
+		{5, 135},
+	} {
+		total := tt.total
+		tc.lineqServer.SetStats(shop.Spec.RoomName(), lineq.RoomStats{ActiveUsers: 15, AdmittedUsers: &total})
+		tc.syncStats(ctx)
+		if got := testutil.ToFloat64(roomAdmittedUsers.WithLabelValues(shopLabels...)); got != tt.want {
+			t.Errorf("expected %v admitted users after a total of %d, got %v", tt.want, tt.total, got)
+		}
+	}
+
+	if err := tc.wrInformer.Indexer("admitted").Delete(shop); err != nil {
+		t.Fatal(err)
+	}
+	tc.syncStats(ctx)
+	if roomAdmittedUsers.DeleteLabelValues(shopLabels...) {
+		t.Error("expected admitted users series of deleted room to be removed")
+	}
+}
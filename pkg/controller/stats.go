@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq"
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+func (c *Controller) syncStats(ctx context.Context) {
+	managed := make(map[string]bool)
+	for _, obj := range c.wrInformer.List() {
+		wr, ok := obj.(*wrv1alpha1.WaitingRoom)
+		if !ok {
+			c.logger.Errorf("unexpected object %v", obj)
+			continue
+		}
+		if wr.DeletionTimestamp != nil {
+			continue
+		}
+		managed[wr.Namespace+"/"+wr.Name] = true
+
+		name := c.createName(wr)
+		stats, err := c.lineq.GetRoom(ctx, name)
+		if lineq.IsNotFound(err) && meta.IsStatusConditionTrue(wr.Status.Conditions, wrv1alpha1.ConditionBackendRegistered) {
+			c.reregister(ctx, wr, name)
+		}
+		if err != nil {
+			c.logger.Errorf("error getting stats for room '%s': %v", name, err)
+			c.setRoomMetrics(wr, nil)
+			continue
+		}
+		c.setRoomMetrics(wr, &stats)
+		if err := c.updateStats(ctx, wr, stats); err != nil {
+			c.logger.Errorf("error updating stats for room '%s': %v", name, err)
+		}
+	}
+	c.pruneRoomMetrics(managed)
+	managedWaitingRooms.Set(float64(len(managed)))
+}
+
+// reregister marks the registration of a room LineQ no longer knows, after a
+// LineQ restart for instance, as failed and queues the room so reconcile
+// registers it again.
+func (c *Controller) reregister(ctx context.Context, wr *wrv1alpha1.WaitingRoom, name string) {
+	c.logger.Infof("room '%s' is missing from LineQ, registering it again", name)
+	if !conditionCurrent(wr, wrv1alpha1.ConditionBackendRegistered, metav1.ConditionFalse, reasonRoomMissing) {
+		c.recorder.Eventf(wr, corev1.EventTypeWarning, reasonRoomMissing, "room '%s' is missing from LineQ", name)
+	}
+	if err := c.updateStatus(ctx, wr, backendRegistered(name, errRoomMissing)); err != nil {
+		c.logger.Errorf("error updating status for room '%s': %v", name, err)
+		return
+	}
+	c.enqueue(wr)
+}
+
+// lastSyncRefreshInterval bounds how often unchanged stats are written, just
+// to keep lastSyncTime fresh.
+const lastSyncRefreshInterval = time.Minute
+
+func (c *Controller) updateStats(ctx context.Context, wr *wrv1alpha1.WaitingRoom, stats lineq.RoomStats) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := c.wrClientSet.LineqV1alpha1().
+			WaitingRooms(wr.Namespace).
+			Get(ctx, wr.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if latest.Status.LastSyncTime != nil &&
+			time.Since(latest.Status.LastSyncTime.Time) < lastSyncRefreshInterval &&
+			latest.Status.ActiveUsers == stats.ActiveUsers &&
+			latest.Status.WaitingUsers == stats.WaitingUsers {
+			return nil
+		}
+
+		now := metav1.Now()
+		status := latest.Status.DeepCopy()
+		status.ActiveUsers = stats.ActiveUsers
+		status.WaitingUsers = stats.WaitingUsers
+		status.LastSyncTime = &now
+
+		return c.applyStatus(ctx, latest, *status)
+	})
+}
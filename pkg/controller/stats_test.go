@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq"
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestUpdateStatsSkipsUnchanged(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	now := metav1.Now()
+	room.Status.ActiveUsers = 15
+	room.Status.WaitingUsers = 40
+	room.Status.LastSyncTime = &now
+	tc := newTestController(t, room)
+	ctx := context.Background()
+
+	if err := tc.updateStats(ctx, room, lineq.RoomStats{ActiveUsers: 15, WaitingUsers: 40}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, action := range tc.wrClientSet.Actions() {
+		if action.GetVerb() != "get" {
+			t.Errorf("expected no writes for unchanged stats, got %s %s", action.GetVerb(), action.GetSubresource())
+		}
+	}
+
+	if err := tc.updateStats(ctx, room, lineq.RoomStats{ActiveUsers: 0, WaitingUsers: 0}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertApplied(t, tc.wrClientSet.Actions(), "waitingrooms", "status")
+	latest := tc.getWaitingRoom(t, "test", "shop")
+	if latest.Status.ActiveUsers != 0 || latest.Status.WaitingUsers != 0 {
+		t.Errorf("expected stats to drop to 0, got %d active and %d waiting", latest.Status.ActiveUsers, latest.Status.WaitingUsers)
+	}
+}
+
+func TestUpdateStatsRefreshesLastSyncTime(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	stale := metav1.NewTime(time.Now().Add(-2 * lastSyncRefreshInterval))
+	room.Status.ActiveUsers = 15
+	room.Status.WaitingUsers = 40
+	room.Status.LastSyncTime = &stale
+	tc := newTestController(t, room)
+
+	if err := tc.updateStats(context.Background(), room, lineq.RoomStats{ActiveUsers: 15, WaitingUsers: 40}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertApplied(t, tc.wrClientSet.Actions(), "waitingrooms", "status")
+	latest := tc.getWaitingRoom(t, "test", "shop")
+	if latest.Status.LastSyncTime == nil || !latest.Status.LastSyncTime.After(stale.Time) {
+		t.Errorf("expected lastSyncTime to be refreshed, got %v", latest.Status.LastSyncTime)
+	}
+}
+
+func TestUpdateStatsRetriesOnConflict(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	room.ResourceVersion = "1"
+	tc := newTestController(t, room)
+	tracker := tc.wrClientSet.Tracker()
+	gvr := wrv1alpha1.SchemeGroupVersion.WithResource("waitingrooms")
+
+	var patches []string
+	tc.wrClientSet.PrependReactor("patch", "waitingrooms", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "status" {
+			return false, nil, nil
+		}
+		patches = append(patches, string(action.(k8stesting.PatchAction).GetPatch()))
+		if len(patches) > 1 {
+			return false, nil, nil
+		}
+		// A reconcile writes its conditions between the stats read and apply.
+		concurrent := room.DeepCopy()
+		concurrent.ResourceVersion = "2"
+		meta.SetStatusCondition(&concurrent.Status.Conditions, metav1.Condition{
+			Type:   wrv1alpha1.ConditionIngressReady,
+			Status: metav1.ConditionTrue,
+			Reason: "Synced",
+		})
+		if err := tracker.Update(gvr, concurrent, "test"); err != nil {
+			t.Fatal(err)
+		}
+		return true, nil, apierrors.NewConflict(gvr.GroupResource(), "shop", nil)
+	})
+
+	if err := tc.updateStats(context.Background(), room, lineq.RoomStats{ActiveUsers: 15, WaitingUsers: 40}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(patches) != 2 {
+		t.Fatalf("expected the stats apply to be retried once, got %d applies", len(patches))
+	}
+	for i, rv := range []string{"1", "2"} {
+		if !strings.Contains(patches[i], `"resourceVersion":"`+rv+`"`) {
+			t.Errorf("expected apply %d to carry resourceVersion %s, got %s", i, rv, patches[i])
+		}
+	}
+	latest := tc.getWaitingRoom(t, "test", "shop")
+	if !meta.IsStatusConditionTrue(latest.Status.Conditions, wrv1alpha1.ConditionIngressReady) {
+		t.Error("expected the concurrent condition to survive the stats write")
+	}
+	if latest.Status.ActiveUsers != 15 || latest.Status.WaitingUsers != 40 {
+		t.Errorf("expected 15 active and 40 waiting users, got %d and %d", latest.Status.ActiveUsers, latest.Status.WaitingUsers)
+	}
+}
+
+func TestSyncStatsReregistersMissingRoom(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	tc := newTestController(t, room)
+	ctx := context.Background()
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEvents(t, tc.recorder, "Normal Registered", "Normal IngressSynced")
+
+	if err := tc.wrInformer.Indexer("test").Update(tc.getWaitingRoom(t, "test", "shop")); err != nil {
+		t.Fatal(err)
+	}
+
+	// LineQ restarted and lost the room.
+	if err := tc.lineq.DeleteRoom(ctx, "example_com_checkout"); err != nil {
+		t.Fatal(err)
+	}
+	tc.syncStats(ctx)
+
+	assertEvents(t, tc.recorder, "Warning RoomMissing")
+	condition := meta.FindStatusCondition(tc.getWaitingRoom(t, "test", "shop").Status.Conditions, wrv1alpha1.ConditionBackendRegistered)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != reasonRoomMissing {
+		t.Errorf("expected %s to be false with reason %s, got %v", wrv1alpha1.ConditionBackendRegistered, reasonRoomMissing, condition)
+	}
+	if tc.queue.Len() != 1 {
+		t.Fatalf("expected the room to be queued, got %d items", tc.queue.Len())
+	}
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEvents(t, tc.recorder, "Normal Registered")
+	if _, ok := tc.lineqServer.Rooms()["example_com_checkout"]; !ok {
+		t.Error("expected the room to be registered again")
+	}
+}
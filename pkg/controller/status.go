@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	wrapply "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1/apis/applyconfiguration/waitingroom/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/util/retry"
+)
+
+type statusMutation func(status *wrv1alpha1.WaitingRoomStatus, generation int64)
+
+// updateStatus records the outcome of reconciling wr. Conditions are stamped
+// with the generation of wr, the one that was acted on, so a spec change that
+// lands during the reconcile isn't reported as done.
+func (c *Controller) updateStatus(ctx context.Context, wr *wrv1alpha1.WaitingRoom, mutations ...statusMutation) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := c.wrClientSet.LineqV1alpha1().
+			WaitingRooms(wr.Namespace).
+			Get(ctx, wr.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if latest.Status.ObservedGeneration > wr.Generation {
+			c.logger.Debugf("waiting room '%s/%s' status already observed generation %d, skipping", wr.Namespace, wr.Name, latest.Status.ObservedGeneration)
+			return nil
+		}
+
+		status := latest.Status.DeepCopy()
+		for _, mutate := range mutations {
+			mutate(status, wr.Generation)
+		}
+		setReadyCondition(status, wr.Generation)
+		status.ObservedGeneration = wr.Generation
+
+		if reflect.DeepEqual(*status, latest.Status) {
+			return nil
+		}
+		return c.applyStatus(ctx, latest, *status)
+	})
+}
+
+// applyStatus writes the complete status owned by the operator, fields left
+// out of an apply are removed from the field manager's set. The apply is
+// conditional on the resourceVersion of latest, the object status was built
+// from, so a concurrent status write turns into a conflict instead of being
+// reverted.
+func (c *Controller) applyStatus(ctx context.Context, latest *wrv1alpha1.WaitingRoom, status wrv1alpha1.WaitingRoomStatus) error {
+	_, err := c.wrClientSet.LineqV1alpha1().
+		WaitingRooms(latest.Namespace).
+		ApplyStatus(
+			ctx,
+			wrapply.WaitingRoom(latest.Name, latest.Namespace).
+				WithResourceVersion(latest.ResourceVersion).
+				WithStatus(statusApplyConfiguration(status)),
+			metav1.ApplyOptions{FieldManager: fieldManager, Force: true},
+		)
+	return err
+}
+
+func statusApplyConfiguration(status wrv1alpha1.WaitingRoomStatus) *wrapply.WaitingRoomStatusApplyConfiguration {
+	apply := wrapply.WaitingRoomStatus().
+		WithObservedGeneration(status.ObservedGeneration).
+		WithIngressName(status.IngressName).
+		WithRoomName(status.RoomName).
+		WithActiveUsers(status.ActiveUsers).
+		WithWaitingUsers(status.WaitingUsers)
+	for _, condition := range status.Conditions {
+		apply.WithConditions(condition)
+	}
+	if status.LastSyncTime != nil {
+		apply.WithLastSyncTime(*status.LastSyncTime)
+	}
+	return apply
+}
+
+func specValid(errs field.ErrorList) statusMutation {
+	return func(status *wrv1alpha1.WaitingRoomStatus, generation int64) {
+		condition := metav1.Condition{
+			Type:               wrv1alpha1.ConditionSpecValid,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             "Valid",
+			Message:            "spec is valid",
+		}
+		if len(errs) > 0 {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = reasonValidationFailed
+			condition.Message = errs.ToAggregate().Error()
+		}
+		meta.SetStatusCondition(&status.Conditions, condition)
+	}
+}
+
+func backendRegistered(roomName string, err error) statusMutation {
+	return func(status *wrv1alpha1.WaitingRoomStatus, generation int64) {
+		status.RoomName = roomName
+		condition := metav1.Condition{
+			Type:               wrv1alpha1.ConditionBackendRegistered,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             "Registered",
+			Message:            "room registered with LineQ",
+		}
+		if err != nil {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "RegistrationFailed"
+			condition.Message = err.Error()
+			if errors.Is(err, errRoomMissing) {
+				condition.Reason = reasonRoomMissing
+			}
+		}
+		meta.SetStatusCondition(&status.Conditions, condition)
+	}
+}
+
+func backendResolved(err error) statusMutation {
+	return func(status *wrv1alpha1.WaitingRoomStatus, generation int64) {
+		condition := metav1.Condition{
+			Type:               wrv1alpha1.ConditionBackendResolved,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             "Resolved",
+			Message:            "backend service resolved",
+		}
+		if err != nil {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "ResolutionFailed"
+			condition.Message = err.Error()
+			var resolveErr *resolveError
+			if errors.As(err, &resolveErr) {
+				condition.Reason = resolveErr.reason
+			}
+		}
+		meta.SetStatusCondition(&status.Conditions, condition)
+	}
+}
+
+func ingressReady(ingressName string, err error) statusMutation {
+	return func(status *wrv1alpha1.WaitingRoomStatus, generation int64) {
+		condition := metav1.Condition{
+			Type:               wrv1alpha1.ConditionIngressReady,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             "Synced",
+			Message:            "ingress is up to date",
+		}
+		if err != nil {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "SyncFailed"
+			condition.Message = err.Error()
+		} else {
+			status.IngressName = ingressName
+		}
+		meta.SetStatusCondition(&status.Conditions, condition)
+	}
+}
+
+func setReadyCondition(status *wrv1alpha1.WaitingRoomStatus, generation int64) {
+	condition := metav1.Condition{
+		Type:               wrv1alpha1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "Ready",
+		Message:            "waiting room is ready",
+	}
+	for _, t := range []string{
+		wrv1alpha1.ConditionSpecValid,
+		wrv1alpha1.ConditionBackendRegistered,
+		wrv1alpha1.ConditionBackendResolved,
+		wrv1alpha1.ConditionIngressReady,
+	} {
+		if !meta.IsStatusConditionTrue(status.Conditions, t) {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "NotReady"
+			condition.Message = t + " condition is not true"
+			break
+		}
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+}
@@ -1,143 +1,271 @@
 package controller
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
-	"strings"
+	"time"
 
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq"
 	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	"github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1/validation"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
-
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
 )
 
 const maxRetries = 3
 
-type RequestBody struct {
-	Name        string `json:"name"`
-	Path        string `json:"path"`
-	ActiveUsers int    `json:"activeUsers"`
-	Host        string `json:"host"`
-}
+const (
+	waitingRoomQueueName   = "waitingrooms"
+	haproxyConfigQueueName = "haproxy-configs"
+)
 
-type ResponseBody struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
-}
+type syncFunc func(ctx context.Context, key string) error
 
 func (c *Controller) runWorker(ctx context.Context) {
-	for c.processNextItem(ctx) {
+	for c.processNextItem(ctx, waitingRoomQueueName, c.queue, c.reconcile, c.waitingRoomObject) {
 	}
 }
 
-func (c *Controller) processNextItem(ctx context.Context) bool {
-	obj, shutdown := c.queue.Get()
+func (c *Controller) runConfigWorker(ctx context.Context) {
+	for c.processNextItem(ctx, haproxyConfigQueueName, c.configQueue, c.processSyncHAProxyConfig, c.configMapObject) {
+	}
+}
+
+func (c *Controller) processNextItem(
+	ctx context.Context,
+	name string,
+	queue workqueue.RateLimitingInterface,
+	sync syncFunc,
+	object objectFunc,
+) bool {
+	obj, shutdown := queue.Get()
 	if shutdown {
 		return false
 	}
-	defer c.queue.Done(obj)
+	defer queue.Done(obj)
+
+	key, ok := obj.(string)
+	if !ok {
+		c.logger.Error("unexpected item ", obj)
+		queue.Forget(obj)
+		return true
+	}
 
-	err := c.processEvent(ctx, obj)
+	start := time.Now()
+	err := sync(ctx, key)
 	if err == nil {
-		c.logger.Debug("processed item")
-		c.queue.Forget(obj)
-	} else if c.queue.NumRequeues(obj) < maxRetries {
-		c.logger.Errorf("error processing event: %v, retrying", err)
-		c.queue.AddRateLimited(obj)
+		c.logger.Debugf("processed '%s'", key)
+		queue.Forget(obj)
+		observeSync(name, syncResultSuccess, start)
+	} else if queue.NumRequeues(obj) < maxRetries {
+		c.logger.Errorf("error processing '%s': %v, retrying", key, err)
+		queue.AddRateLimited(obj)
+		observeSync(name, syncResultRetry, start)
 	} else {
-		c.logger.Errorf("error processing event: %v, max retries reached", err)
-		c.queue.Forget(obj)
+		c.logger.Errorf("error processing '%s': %v, max retries reached", key, err)
+		queue.Forget(obj)
+		observeSync(name, syncResultDropped, start)
+		if o := object(key); o != nil {
+			c.recorder.Eventf(o, corev1.EventTypeWarning, reasonRetriesExhausted, "giving up after %d retries: %v", maxRetries, err)
+		}
 		utilruntime.HandleError(err)
 	}
 
 	return true
 }
 
-func (c *Controller) processEvent(ctx context.Context, obj interface{}) error {
-	event, ok := obj.(event)
-	if !ok {
-		c.logger.Error("unexpected event ", obj)
+// reconcile converges the WaitingRoom stored under key towards its spec. It
+// reads the latest object from the informer, so repeated or stale keys are
+// harmless.
+func (c *Controller) reconcile(ctx context.Context, key string) error {
+	obj, exists, err := c.wrInformer.GetByKey(key)
+	if err != nil {
+		return fmt.Errorf("error getting waiting room '%s' %v", key, err)
+	}
+	if !exists {
+		c.logger.Debugf("waiting room '%s' no longer exists", key)
 		return nil
 	}
-	switch event.eventType {
-	case addWaitingRoom:
-		return c.processAddWaitingRoom(ctx, event.newObj.(*wrv1alpha1.WaitingRoom))
+	wr, ok := obj.(*wrv1alpha1.WaitingRoom)
+	if !ok {
+		return fmt.Errorf("unexpected object %v", obj)
 	}
-	return nil
-}
+	wr = wr.DeepCopy()
 
-func (c *Controller) sendBackendRequest(wr *wrv1alpha1.WaitingRoom, name string) {
-	url := fmt.Sprintf("http://%s:%d/create", c.config.LineqHttpAddr, c.config.LineqHttpPort)
+	if wr.DeletionTimestamp != nil {
+		return c.finalize(ctx, wr)
+	}
 
-	requestBody := RequestBody{
-		Name:        name,
-		Path:        wr.Spec.Path,
-		ActiveUsers: wr.Spec.ActiveUsers,
-		Host:        wr.Spec.Host,
+	if err := c.addFinalizer(ctx, wr); err != nil {
+		return fmt.Errorf("error adding finalizer %v", err)
 	}
 
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		c.logger.Infof("Error encoding JSON: %v", err)
-		return
+	// Without the admission webhook invalid specs reach the controller, they
+	// are reported and left alone until the spec changes.
+	if errs := validation.ValidateWaitingRoom(wr); len(errs) > 0 {
+		c.logger.Infof("waiting room '%s' is invalid: %v", key, errs.ToAggregate())
+		if !conditionCurrent(wr, wrv1alpha1.ConditionSpecValid, metav1.ConditionFalse, reasonValidationFailed) {
+			c.recorder.Event(wr, corev1.EventTypeWarning, reasonValidationFailed, errs.ToAggregate().Error())
+		}
+		if err := c.updateStatus(ctx, wr, specValid(errs)); err != nil {
+			return fmt.Errorf("error updating status %v", err)
+		}
+		return nil
 	}
 
-	response, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		c.logger.Infof("Error sending request: %v", err)
-		return
+	mutations := []statusMutation{specValid(nil)}
+	var backendErr error
+
+	if !backendRegistrationCurrent(wr) {
+		var name string
+		name, backendErr = c.registerRoom(ctx, wr)
+		mutations = append(mutations, backendRegistered(name, backendErr))
+		if backendErr != nil {
+			c.recorder.Event(wr, corev1.EventTypeWarning, reasonRegistrationFailed, backendErr.Error())
+		} else {
+			c.recorder.Eventf(wr, corev1.EventTypeNormal, reasonRegistered, "registered room '%s' with LineQ", name)
+		}
 	}
-	defer response.Body.Close()
 
-	var responseBody ResponseBody
-	err = json.NewDecoder(response.Body).Decode(&responseBody)
-	if err != nil {
-		c.logger.Infof("Error decoding JSON response: %v", err)
-		return
+	ingMutations, ingErr := c.ensureIngress(ctx, wr)
+	mutations = append(mutations, ingMutations...)
+
+	if err := c.updateStatus(ctx, wr, mutations...); err != nil {
+		return fmt.Errorf("error updating status %v", err)
+	}
+
+	if backendErr != nil {
+		return backendErr
 	}
+	return ingErr
+}
 
-	c.logger.Infof("Response Status: '%s'", responseBody.Status)
-	c.logger.Infof("Response Message: '%s'", responseBody.Message)
+func (c *Controller) createRoom(wr *wrv1alpha1.WaitingRoom, name string) lineq.Room {
+	paths := wr.Spec.GetPaths()
+	room := lineq.Room{
+		Name:        name,
+		Path:        paths[0].Path,
+		ActiveUsers: wr.Spec.ActiveUsers,
+		Host:        wr.Spec.Host,
+	}
+	for _, p := range paths {
+		room.Paths = append(room.Paths, p.Path)
+	}
+	return room
 }
 
 func (c *Controller) createName(wr *wrv1alpha1.WaitingRoom) string {
-	domain := strings.Replace(wr.Spec.Host, ".", "_", -1)
-	path := strings.Replace(wr.Spec.Path, "/", "_", -1)
-	name := fmt.Sprintf("%s%s", domain, path)
+	return wr.Spec.RoomName()
+}
 
-	return name
+// backendRegistrationCurrent reports whether LineQ already holds the room
+// for the current generation of the spec.
+func backendRegistrationCurrent(wr *wrv1alpha1.WaitingRoom) bool {
+	condition := meta.FindStatusCondition(wr.Status.Conditions, wrv1alpha1.ConditionBackendRegistered)
+	return condition != nil &&
+		condition.Status == metav1.ConditionTrue &&
+		condition.ObservedGeneration == wr.Generation &&
+		wr.Status.RoomName == wr.Spec.RoomName()
 }
 
-func (c *Controller) processAddWaitingRoom(ctx context.Context, wr *wrv1alpha1.WaitingRoom) error {
+// registerRoom creates or updates the LineQ room and drops the room recorded
+// in the status when the host or path change its name.
+func (c *Controller) registerRoom(ctx context.Context, wr *wrv1alpha1.WaitingRoom) (string, error) {
 	name := c.createName(wr)
-	c.sendBackendRequest(wr, name)
+	room := c.createRoom(wr, name)
+
+	err := c.lineq.CreateRoom(ctx, room)
+	if lineq.IsAlreadyExists(err) {
+		err = c.lineq.UpdateRoom(ctx, room)
+	}
+	if err != nil {
+		return name, fmt.Errorf("error registering room '%s': %w", name, err)
+	}
 
-	ing := createIngress(wr, wr.Namespace)
-	exists, err := resourceExists(ing, c.ingInformer.GetIndexer())
+	if oldName := wr.Status.RoomName; oldName != "" && oldName != name {
+		if err := c.lineq.DeleteRoom(ctx, oldName); err != nil && !lineq.IsNotFound(err) {
+			c.logger.Errorf("error deleting room '%s': %v", oldName, err)
+		}
+	}
+	return name, nil
+}
+
+var (
+	errBackendNotResolved = errors.New("backend service not resolved")
+	errRoomMissing        = errors.New("room is missing from LineQ")
+)
+
+// ensureIngress resolves the backend and applies the Ingress. A missing or
+// misconfigured backend is only reported in the status, the room is synced
+// again once the Service changes.
+func (c *Controller) ensureIngress(ctx context.Context, wr *wrv1alpha1.WaitingRoom) ([]statusMutation, error) {
+	backend, err := c.resolveBackend(wr)
 	if err != nil {
-		return fmt.Errorf("error checking ingress existence %v", err)
+		mutations := []statusMutation{
+			backendResolved(err),
+			ingressReady("", errBackendNotResolved),
+		}
+		var resolveErr *resolveError
+		if errors.As(err, &resolveErr) {
+			c.logger.Infof("waiting room '%s/%s' backend not resolved: %v", wr.Namespace, wr.Name, err)
+			if !conditionCurrent(wr, wrv1alpha1.ConditionBackendResolved, metav1.ConditionFalse, resolveErr.reason) {
+				c.recorder.Event(wr, corev1.EventTypeWarning, resolveErr.reason, resolveErr.Error())
+			}
+			return mutations, nil
+		}
+		return mutations, err
+	}
+
+	ing := createIngress(wr, wr.Namespace, backend)
+	ingErr := c.applyIngress(ctx, ing)
+	if ingErr != nil {
+		c.recorder.Eventf(wr, corev1.EventTypeWarning, reasonIngressSyncFailed, "error applying ingress '%s': %v", *ing.Name, ingErr)
+	} else if !conditionCurrent(wr, wrv1alpha1.ConditionIngressReady, metav1.ConditionTrue, "Synced") {
+		c.recorder.Eventf(wr, corev1.EventTypeNormal, reasonIngressSynced, "applied ingress '%s'", *ing.Name)
 	}
-	if exists {
-		c.logger.Debug("ingress already exists, skipping")
+	return []statusMutation{
+		backendResolved(nil),
+		ingressReady(*ing.Name, ingErr),
+	}, ingErr
+}
+
+func (c *Controller) finalize(ctx context.Context, wr *wrv1alpha1.WaitingRoom) error {
+	if !hasFinalizer(wr) {
+		c.logger.Debug("finalizer already removed, skipping")
 		return nil
 	}
 
-	_, err = c.kubeClientSet.NetworkingV1().
+	err := c.kubeClientSet.NetworkingV1().
 		Ingresses(wr.Namespace).
-		Create(ctx, ing, metav1.CreateOptions{})
+		Delete(ctx, wr.Name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting ingress %v", err)
+	}
 
-	return err
+	names := []string{c.createName(wr)}
+	if wr.Status.RoomName != "" && wr.Status.RoomName != names[0] {
+		names = append(names, wr.Status.RoomName)
+	}
+	for _, name := range names {
+		if err := c.lineq.DeleteRoom(ctx, name); err != nil && !lineq.IsNotFound(err) {
+			return fmt.Errorf("error deleting room '%s': %w", name, err)
+		}
+	}
+
+	return c.removeFinalizer(ctx, wr)
 }
 
-func resourceExists(obj interface{}, indexer cache.Indexer) (bool, error) {
-	key, err := cache.MetaNamespaceKeyFunc(obj)
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
 	if err != nil {
-		return false, fmt.Errorf("error getting key %v", err)
+		c.logger.Errorf("error getting key %v", err)
+		return
 	}
-	_, exists, err := indexer.GetByKey(key)
-	return exists, err
+	c.queue.Add(key)
 }
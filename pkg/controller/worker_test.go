@@ -0,0 +1,437 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq"
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq/lineqtest"
+	wr "github.com/hamedetemaad/lineq-operator/pkg/waitingroom"
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+
+	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestReconcile(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	tc := newTestController(t, room)
+	ctx := context.Background()
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertApplied(t, tc.kubeClientSet.Actions(), "ingresses", "")
+	assertApplied(t, tc.wrClientSet.Actions(), "waitingrooms", "status")
+	assertLineqCalls(t, tc.lineqServer.Calls(),
+		lineqCall("create", checkoutRoom(20)),
+	)
+
+	rooms := tc.lineqServer.Rooms()
+	lineqRoom, ok := rooms["example_com_checkout"]
+	if !ok {
+		t.Fatalf("expected room 'example_com_checkout' to be registered, got %v", rooms)
+	}
+	if lineqRoom.Host != "example.com" || lineqRoom.Path != "/checkout" || lineqRoom.ActiveUsers != 20 {
+		t.Errorf("unexpected room %+v", lineqRoom)
+	}
+
+	ing, err := tc.kubeClientSet.NetworkingV1().
+		Ingresses("test").
+		Get(ctx, "shop", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting ingress: %v", err)
+	}
+	assertIngress(t, ing, "example.com.vwr", "/checkout", "shop", 80)
+	if len(ing.OwnerReferences) != 1 || ing.OwnerReferences[0].Name != "shop" {
+		t.Errorf("expected ingress to be owned by the waiting room, got %v", ing.OwnerReferences)
+	}
+
+	got := tc.getWaitingRoom(t, "test", "shop")
+	if !hasFinalizer(got) {
+		t.Errorf("expected finalizer %s, got %v", wr.WaitingRoomFinalizer, got.Finalizers)
+	}
+	for _, action := range tc.wrClientSet.Actions() {
+		if action.GetResource().Resource != "waitingrooms" || action.GetSubresource() != "" {
+			continue
+		}
+		if action.GetVerb() == "update" {
+			t.Errorf("expected finalizer to be patched, got update")
+		}
+		if a, ok := action.(k8stesting.PatchAction); ok && a.GetPatchType() != types.MergePatchType {
+			t.Errorf("expected finalizer merge patch, got %s patch", a.GetPatchType())
+		}
+	}
+	if got.Status.RoomName != "example_com_checkout" {
+		t.Errorf("expected room name 'example_com_checkout', got '%s'", got.Status.RoomName)
+	}
+	if got.Status.IngressName != "shop" {
+		t.Errorf("expected ingress name 'shop', got '%s'", got.Status.IngressName)
+	}
+	if got.Status.ObservedGeneration != 1 {
+		t.Errorf("expected observed generation 1, got %d", got.Status.ObservedGeneration)
+	}
+	for _, c := range []string{
+		wrv1alpha1.ConditionBackendRegistered,
+		wrv1alpha1.ConditionIngressReady,
+		wrv1alpha1.ConditionReady,
+	} {
+		if !meta.IsStatusConditionTrue(got.Status.Conditions, c) {
+			t.Errorf("expected condition %s to be true, got %v", c, got.Status.Conditions)
+		}
+	}
+}
+
+func TestReconcileMultiplePaths(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	room.Spec.Path = ""
+	room.Spec.Paths = []wrv1alpha1.WaitingRoomPath{
+		{Path: "/cart"},
+		{Path: "/checkout", PathType: wrv1alpha1.PathTypeExact},
+		{Path: "/pay", PathType: wrv1alpha1.PathTypePrefix},
+	}
+	tc := newTestController(t, room)
+	ctx := context.Background()
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertLineqCalls(t, tc.lineqServer.Calls(),
+		lineqCall("create", lineq.Room{
+			Name:        "example_com_cart",
+			Path:        "/cart",
+			Paths:       []string{"/cart", "/checkout", "/pay"},
+			ActiveUsers: 20,
+			Host:        "example.com",
+		}),
+	)
+
+	rooms := tc.lineqServer.Rooms()
+	if len(rooms) != 1 {
+		t.Fatalf("expected a single room, got %v", rooms)
+	}
+	lineqRoom, ok := rooms["example_com_cart"]
+	if !ok {
+		t.Fatalf("expected room 'example_com_cart', got %v", rooms)
+	}
+	if want := []string{"/cart", "/checkout", "/pay"}; !reflect.DeepEqual(lineqRoom.Paths, want) {
+		t.Errorf("expected paths %v, got %v", want, lineqRoom.Paths)
+	}
+
+	ing, err := tc.kubeClientSet.NetworkingV1().
+		Ingresses("test").
+		Get(ctx, "shop", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting ingress: %v", err)
+	}
+	paths := ing.Spec.Rules[0].HTTP.Paths
+	if len(paths) != 3 {
+		t.Fatalf("expected 3 ingress paths, got %d", len(paths))
+	}
+	for i, want := range []netv1.PathType{netv1.PathTypeExact, netv1.PathTypeExact, netv1.PathTypePrefix} {
+		if *paths[i].PathType != want {
+			t.Errorf("expected path %s to be %s, got %s", paths[i].Path, want, *paths[i].PathType)
+		}
+	}
+}
+
+func TestReconcileBackendError(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	tc := newTestController(t, room)
+	tc.lineqServer.FailNext("create", http.StatusInternalServerError)
+	ctx := context.Background()
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err == nil {
+		t.Fatal("expected error when lineq fails")
+	}
+	if rooms := tc.lineqServer.Rooms(); len(rooms) != 0 {
+		t.Errorf("expected no rooms, got %v", rooms)
+	}
+
+	if _, err := tc.kubeClientSet.NetworkingV1().
+		Ingresses("test").
+		Get(ctx, "shop", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected ingress to be created regardless of lineq errors: %v", err)
+	}
+
+	got := tc.getWaitingRoom(t, "test", "shop")
+	if !meta.IsStatusConditionFalse(got.Status.Conditions, wrv1alpha1.ConditionBackendRegistered) {
+		t.Errorf("expected %s to be false, got %v", wrv1alpha1.ConditionBackendRegistered, got.Status.Conditions)
+	}
+	if !meta.IsStatusConditionFalse(got.Status.Conditions, wrv1alpha1.ConditionReady) {
+		t.Errorf("expected %s to be false, got %v", wrv1alpha1.ConditionReady, got.Status.Conditions)
+	}
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error on retry: %v", err)
+	}
+	if _, ok := tc.lineqServer.Rooms()["example_com_checkout"]; !ok {
+		t.Error("expected room to be registered on retry")
+	}
+	assertLineqCalls(t, tc.lineqServer.Calls(),
+		lineqCall("create", lineq.Room{}),
+		lineqCall("create", checkoutRoom(20)),
+	)
+}
+
+func TestReconcileExistingRoom(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	tc := newTestController(t, room.DeepCopy())
+	tc.lineqServer.AddRoom(tc.createRoom(room, "example_com_checkout"))
+
+	latest := tc.getWaitingRoom(t, "test", "shop")
+	latest.Spec.ActiveUsers = 50
+	tc.updateWaitingRoomFixture(t, latest)
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := tc.lineqServer.Rooms()["example_com_checkout"].ActiveUsers; got != 50 {
+		t.Errorf("expected existing room to be updated to 50 active users, got %d", got)
+	}
+	assertLineqCalls(t, tc.lineqServer.Calls(),
+		lineqCall("create", checkoutRoom(50)),
+		lineqCall("update", checkoutRoom(50)),
+	)
+}
+
+func TestReconcileSpecChangedDuringReconcile(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	room.Generation = 2
+	room.Spec.ActiveUsers = 50
+	tc := newTestController(t, room.DeepCopy())
+	ctx := context.Background()
+
+	// The cache still holds generation 2 when generation 3 reaches the API server.
+	if err := tc.wrInformer.Indexer("test").Add(room); err != nil {
+		t.Fatal(err)
+	}
+	latest := tc.getWaitingRoom(t, "test", "shop")
+	latest.Generation = 3
+	latest.Spec.ActiveUsers = 100
+	tc.updateWaitingRoomFixture(t, latest)
+
+	if err := tc.reconcile(ctx, "test/shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := tc.getWaitingRoom(t, "test", "shop")
+	if got.Status.ObservedGeneration != 2 {
+		t.Errorf("expected observed generation 2, got %d", got.Status.ObservedGeneration)
+	}
+	if backendRegistrationCurrent(got) {
+		t.Error("expected registration of generation 3 to be pending")
+	}
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertLineqCalls(t, tc.lineqServer.Calls(),
+		lineqCall("create", checkoutRoom(50)),
+		lineqCall("create", checkoutRoom(100)),
+		lineqCall("update", checkoutRoom(100)),
+	)
+	if got := tc.getWaitingRoom(t, "test", "shop"); got.Status.ObservedGeneration != 3 || !backendRegistrationCurrent(got) {
+		t.Errorf("expected generation 3 to be registered, got %+v", got.Status)
+	}
+}
+
+func TestReconcileIdempotent(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	tc := newTestController(t, room)
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertLineqCalls(t, tc.lineqServer.Calls(),
+		lineqCall("create", checkoutRoom(20)),
+	)
+	calls := len(tc.lineqServer.Calls())
+	tc.wrClientSet.ClearActions()
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := tc.lineqServer.Calls()[calls:]; len(got) != 0 {
+		t.Errorf("expected no lineq calls for an unchanged room, got %v", got)
+	}
+	for _, action := range tc.wrClientSet.Actions() {
+		if action.GetVerb() != "get" {
+			t.Errorf("expected no writes for an unchanged room, got %s %s", action.GetVerb(), action.GetSubresource())
+		}
+	}
+}
+
+func TestReconcileUpdate(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	tc := newTestController(t, room)
+	ctx := context.Background()
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	updated := tc.getWaitingRoom(t, "test", "shop")
+	updated.Spec.Path = "/pay"
+	updated.Spec.ActiveUsers = 100
+	updated.Spec.BackendSvcPort = 8080
+	updated.Generation = 2
+	tc.updateWaitingRoomFixture(t, updated)
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rooms := tc.lineqServer.Rooms()
+	if _, ok := rooms["example_com_checkout"]; ok {
+		t.Error("expected old room to be deleted")
+	}
+	if got, ok := rooms["example_com_pay"]; !ok || got.ActiveUsers != 100 {
+		t.Errorf("expected room 'example_com_pay' with 100 active users, got %v", rooms)
+	}
+	assertLineqCalls(t, tc.lineqServer.Calls(),
+		lineqCall("create", checkoutRoom(20)),
+		lineqCall("create", lineq.Room{
+			Name:        "example_com_pay",
+			Path:        "/pay",
+			Paths:       []string{"/pay"},
+			ActiveUsers: 100,
+			Host:        "example.com",
+		}),
+		lineqCall("delete", lineq.Room{Name: "example_com_checkout"}),
+	)
+
+	ing, err := tc.kubeClientSet.NetworkingV1().Ingresses("test").Get(ctx, "shop", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting ingress: %v", err)
+	}
+	assertIngress(t, ing, "example.com.vwr", "/pay", "shop", 8080)
+
+	got := tc.getWaitingRoom(t, "test", "shop")
+	if got.Status.RoomName != "example_com_pay" || got.Status.ObservedGeneration != 2 {
+		t.Errorf("expected status for generation 2 of 'example_com_pay', got %+v", got.Status)
+	}
+}
+
+func TestReconcileDelete(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	tc := newTestController(t, room)
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deleting := tc.getWaitingRoom(t, "test", "shop")
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+	tc.updateWaitingRoomFixture(t, deleting)
+
+	tc.lineqServer.FailNext("delete", http.StatusServiceUnavailable)
+	if err := tc.reconcileRoom(t, "test", "shop"); err == nil {
+		t.Fatal("expected error when lineq fails")
+	}
+	if !hasFinalizer(tc.getWaitingRoom(t, "test", "shop")) {
+		t.Fatal("expected finalizer to be kept until lineq confirms the deletion")
+	}
+
+	if err := tc.reconcileRoom(t, "test", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rooms := tc.lineqServer.Rooms(); len(rooms) != 0 {
+		t.Errorf("expected room to be deleted, got %v", rooms)
+	}
+	assertLineqCalls(t, tc.lineqServer.Calls(),
+		lineqCall("create", checkoutRoom(20)),
+		lineqCall("delete", lineq.Room{}),
+		lineqCall("delete", lineq.Room{Name: "example_com_checkout"}),
+	)
+	if hasFinalizer(tc.getWaitingRoom(t, "test", "shop")) {
+		t.Error("expected finalizer to be removed")
+	}
+}
+
+func TestReconcileMissingRoom(t *testing.T) {
+	tc := newTestController(t)
+	if err := tc.reconcile(context.Background(), "test/shop"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestEnqueueDeduplicatesKeys(t *testing.T) {
+	room := newWaitingRoom("shop", "test")
+	room.ResourceVersion = "1"
+	tc := newTestController(t)
+
+	tc.addWaitingRoom(room)
+	tc.updateWaitingRoom(room, room)
+	if tc.queue.Len() != 1 {
+		t.Fatalf("expected a single queued key, got %d", tc.queue.Len())
+	}
+
+	statusUpdate := room.DeepCopy()
+	statusUpdate.ResourceVersion = "2"
+	statusUpdate.Status.ActiveUsers = 10
+	item, _ := tc.queue.Get()
+	tc.queue.Done(item)
+	tc.updateWaitingRoom(room, statusUpdate)
+	if tc.queue.Len() != 0 {
+		t.Errorf("expected status only updates to be ignored, got %d items", tc.queue.Len())
+	}
+
+	tc.deleteWaitingRoom(cache.DeletedFinalStateUnknown{Key: "test/shop", Obj: room})
+	if tc.queue.Len() != 1 {
+		t.Errorf("expected deleted room to be queued, got %d items", tc.queue.Len())
+	}
+}
+
+func checkoutRoom(activeUsers int) lineq.Room {
+	return lineq.Room{
+		Name:        "example_com_checkout",
+		Path:        "/checkout",
+		Paths:       []string{"/checkout"},
+		ActiveUsers: activeUsers,
+		Host:        "example.com",
+	}
+}
+
+func lineqCall(endpoint string, room lineq.Room) lineqtest.Call {
+	return lineqtest.Call{Method: http.MethodPost, Endpoint: endpoint, Room: room}
+}
+
+// assertLineqCalls fails unless LineQ received exactly the expected calls,
+// in order. Failed calls are recorded without a room.
+func assertLineqCalls(t *testing.T, got []lineqtest.Call, want ...lineqtest.Call) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected lineq calls %+v, got %+v", want, got)
+	}
+}
+
+func assertIngress(t *testing.T, ing *netv1.Ingress, host, path, svc string, port int32) {
+	t.Helper()
+	if len(ing.Spec.Rules) != 1 {
+		t.Fatalf("expected 1 rule, got %d", len(ing.Spec.Rules))
+	}
+	rule := ing.Spec.Rules[0]
+	if rule.Host != host {
+		t.Errorf("expected host '%s', got '%s'", host, rule.Host)
+	}
+	if rule.HTTP == nil || len(rule.HTTP.Paths) != 1 {
+		t.Fatalf("expected 1 path, got %v", rule.HTTP)
+	}
+	p := rule.HTTP.Paths[0]
+	if p.Path != path {
+		t.Errorf("expected path '%s', got '%s'", path, p.Path)
+	}
+	if p.Backend.Service == nil ||
+		p.Backend.Service.Name != svc ||
+		p.Backend.Service.Port.Number != port {
+		t.Errorf("expected backend %s:%d, got %v", svc, port, p.Backend.Service)
+	}
+}
@@ -0,0 +1,116 @@
+package haproxy
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"text/template"
+)
+
+const (
+	FrontendTemplateKey  = "frontend.cfg.tmpl"
+	AuxiliaryTemplateKey = "auxiliary.cfg.tmpl"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// Values are the fields available to the templates.
+type Values struct {
+	LineqTcpAddr         string
+	LineqTcpPort         int
+	LineqHttpAddr        string
+	LineqHttpPort        int
+	RoomTableName        string
+	UserTableName        string
+	LineqSessionDuration int
+	Rooms                []Room
+	Routes               []Route
+}
+
+// Room is a LineQ room served by HAProxy. Its session cookie is scoped to
+// CookiePath, shared by all the paths of the room, and marked Secure for
+// https rooms even when TLS is terminated in front of HAProxy.
+type Room struct {
+	Name       string
+	CookiePath string
+	Secure     bool
+}
+
+// Route sends requests for Host and Path, or paths under it when Prefix is
+// set, to the stick-table entries of Room. Routes are matched in order.
+type Route struct {
+	Room   string
+	Host   string
+	Path   string
+	Prefix bool
+}
+
+type Renderer struct {
+	frontend  *template.Template
+	auxiliary *template.Template
+}
+
+// New returns a renderer using the default templates, replaced by any
+// template found in overrides under FrontendTemplateKey or AuxiliaryTemplateKey.
+func New(overrides map[string]string) (*Renderer, error) {
+	frontend, err := parseTemplate(FrontendTemplateKey, overrides)
+	if err != nil {
+		return nil, err
+	}
+	auxiliary, err := parseTemplate(AuxiliaryTemplateKey, overrides)
+	if err != nil {
+		return nil, err
+	}
+	return &Renderer{
+		frontend:  frontend,
+		auxiliary: auxiliary,
+	}, nil
+}
+
+func (r *Renderer) RenderFrontend(values Values) (string, error) {
+	return execute(r.frontend, values)
+}
+
+func (r *Renderer) RenderAuxiliary(values Values) (string, error) {
+	return execute(r.auxiliary, values)
+}
+
+// validate refuses to render stick-tables without a name or an expiry.
+func validate(values Values) error {
+	if values.RoomTableName == "" || values.UserTableName == "" {
+		return fmt.Errorf("refusing to render haproxy config with empty table names, room '%s' user '%s'", values.RoomTableName, values.UserTableName)
+	}
+	if values.LineqSessionDuration <= 0 {
+		return fmt.Errorf("refusing to render haproxy config with session duration %d", values.LineqSessionDuration)
+	}
+	return nil
+}
+
+func parseTemplate(name string, overrides map[string]string) (*template.Template, error) {
+	text, ok := overrides[name]
+	if !ok {
+		b, err := defaultTemplates.ReadFile("templates/" + name)
+		if err != nil {
+			return nil, fmt.Errorf("error reading default template %s %v", name, err)
+		}
+		text = string(b)
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template %s %v", name, err)
+	}
+	return tmpl, nil
+}
+
+func execute(tmpl *template.Template, values Values) (string, error) {
+	if err := validate(values); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return "", fmt.Errorf("error rendering template %s %v", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
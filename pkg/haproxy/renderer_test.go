@@ -0,0 +1,167 @@
+package haproxy
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+var testValues = Values{
+	LineqTcpAddr:         "lineq-tcp.lineq.svc",
+	LineqTcpPort:         11111,
+	LineqHttpAddr:        "lineq-http.lineq.svc",
+	LineqHttpPort:        8060,
+	RoomTableName:        "lineq_room",
+	UserTableName:        "lineq_user",
+	LineqSessionDuration: 5,
+	Rooms: []Room{
+		{Name: "example_com_cart", CookiePath: "/"},
+		{Name: "shop_example_com_", CookiePath: "/", Secure: true},
+	},
+	Routes: []Route{
+		{Room: "example_com_cart", Host: "example.com", Path: "/cart"},
+		{Room: "example_com_cart", Host: "example.com", Path: "/checkout"},
+		{Room: "example_com_cart", Host: "example.com", Path: "/pay", Prefix: true},
+		{Room: "shop_example_com_", Host: "shop.example.com", Path: "/", Prefix: true},
+	},
+}
+
+func TestRenderGolden(t *testing.T) {
+	renderer, err := New(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		golden string
+		render func(Values) (string, error)
+	}{
+		{golden: "frontend.golden", render: renderer.RenderFrontend},
+		{golden: "auxiliary.golden", render: renderer.RenderAuxiliary},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			got, err := tt.render(testValues)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertGolden(t, tt.golden, got)
+		})
+	}
+}
+
+func TestRenderRoomPathsShareIndex(t *testing.T) {
+	renderer, err := New(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := renderer.RenderFrontend(testValues)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"http-request set-var(txn.index) str(example_com_cart) if { var(txn.vwr_path) -m found } !{ var(txn.index) -m found } { var(txn.host) -m str example.com } { var(txn.path) -m str /cart }",
+		"http-request set-var(txn.index) str(example_com_cart) if { var(txn.vwr_path) -m found } !{ var(txn.index) -m found } { var(txn.host) -m str example.com } { var(txn.path) -m str /checkout }",
+		"http-request set-var(txn.index) str(example_com_cart) if { var(txn.vwr_path) -m found } !{ var(txn.index) -m found } { var(txn.host) -m str example.com } { var(txn.path) -m beg /pay }",
+		"http-request set-var(txn.cookie_path) str(/) if { var(txn.index) -m str example_com_cart }",
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("expected frontend to contain %q, got\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "set-var(txn.cookie_secure) bool(true) if { var(txn.index) -m str example_com_cart }") {
+		t.Error("expected no secure cookie for http rooms")
+	}
+	if !strings.Contains(got, "set-var(txn.cookie_secure) bool(true) if { var(txn.index) -m str shop_example_com_ }\n") {
+		t.Error("expected a secure cookie for https rooms")
+	}
+	if strings.Contains(got, "var(txn.host),regsub") {
+		t.Error("expected the index not to be derived from the request path")
+	}
+}
+
+func TestRenderOverrides(t *testing.T) {
+	renderer, err := New(map[string]string{
+		AuxiliaryTemplateKey: "backend {{ .UserTableName }} expire {{ .LineqSessionDuration }}m\n",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := renderer.RenderAuxiliary(testValues)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "backend lineq_user expire 5m\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	frontend, err := renderer.RenderFrontend(testValues)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertGolden(t, "frontend.golden", frontend)
+}
+
+func TestRenderInvalidOverride(t *testing.T) {
+	if _, err := New(map[string]string{FrontendTemplateKey: "{{ .RoomTableName "}); err == nil {
+		t.Error("expected error parsing invalid template")
+	}
+
+	renderer, err := New(map[string]string{FrontendTemplateKey: "{{ .Unknown }}"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := renderer.RenderFrontend(testValues); err == nil {
+		t.Error("expected error rendering unknown field")
+	}
+}
+
+func TestRenderInvalidConfig(t *testing.T) {
+	renderer, err := New(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	emptyRoomTable := testValues
+	emptyRoomTable.RoomTableName = ""
+	emptyUserTable := testValues
+	emptyUserTable.UserTableName = ""
+	noSession := testValues
+	noSession.LineqSessionDuration = 0
+
+	for name, values := range map[string]Values{
+		"empty room table": emptyRoomTable,
+		"empty user table": emptyUserTable,
+		"no session":       noSession,
+	} {
+		if _, err := renderer.RenderFrontend(values); err == nil {
+			t.Errorf("%s: expected error rendering frontend", name)
+		}
+		if _, err := renderer.RenderAuxiliary(values); err == nil {
+			t.Errorf("%s: expected error rendering auxiliary", name)
+		}
+	}
+}
+
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("error updating golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error reading golden file: %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("output does not match %s\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,141 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gotway/gotway/pkg/log"
+)
+
+const (
+	HealthzPath = "/healthz"
+	ReadyzPath  = "/readyz"
+)
+
+const (
+	statusOK    = "ok"
+	statusError = "error"
+)
+
+// Check reports the state of a component. The message describes a passing
+// check, failures are reported through the error.
+type Check func(ctx context.Context) (string, error)
+
+type Options struct {
+	Port    string
+	Timeout time.Duration
+}
+
+type Health struct {
+	options Options
+	server  *http.Server
+	logger  log.Logger
+
+	mu              sync.RWMutex
+	livenessChecks  map[string]Check
+	readinessChecks map[string]Check
+}
+
+type checkResult struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+type report struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks,omitempty"`
+}
+
+func (h *Health) AddLivenessCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.livenessChecks[name] = check
+}
+
+func (h *Health) AddReadinessCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readinessChecks[name] = check
+}
+
+func (h *Health) Start() {
+	h.logger.Infof("health server listening in :%s", h.options.Port)
+	if err := h.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		h.logger.Error("error starting health server ", err)
+	}
+}
+
+func (h *Health) Stop() {
+	if err := h.server.Shutdown(context.Background()); err != nil {
+		h.logger.Error("error stopping health server ", err)
+		return
+	}
+	h.logger.Info("stopped health server")
+}
+
+func (h *Health) serveHealthz(rw http.ResponseWriter, r *http.Request) {
+	h.serve(rw, r, h.livenessChecks)
+}
+
+func (h *Health) serveReadyz(rw http.ResponseWriter, r *http.Request) {
+	h.serve(rw, r, h.readinessChecks)
+}
+
+func (h *Health) serve(rw http.ResponseWriter, r *http.Request, checks map[string]Check) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.options.Timeout)
+	defer cancel()
+
+	h.mu.RLock()
+	res := run(ctx, checks)
+	h.mu.RUnlock()
+
+	code := http.StatusOK
+	if res.Status != statusOK {
+		code = http.StatusServiceUnavailable
+		h.logger.Debugf("%s failed: %v", r.URL.Path, res.Checks)
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	if err := json.NewEncoder(rw).Encode(res); err != nil {
+		h.logger.Error("error encoding health report ", err)
+	}
+}
+
+func run(ctx context.Context, checks map[string]Check) report {
+	res := report{
+		Status: statusOK,
+		Checks: make(map[string]checkResult, len(checks)),
+	}
+	for name, check := range checks {
+		message, err := check(ctx)
+		if err != nil {
+			res.Status = statusError
+			res.Checks[name] = checkResult{Status: statusError, Message: err.Error()}
+			continue
+		}
+		res.Checks[name] = checkResult{Status: statusOK, Message: message}
+	}
+	return res
+}
+
+func New(options Options, logger log.Logger) *Health {
+	h := &Health{
+		options:         options,
+		logger:          logger,
+		livenessChecks:  make(map[string]Check),
+		readinessChecks: make(map[string]Check),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HealthzPath, h.serveHealthz)
+	mux.HandleFunc(ReadyzPath, h.serveReadyz)
+	h.server = &http.Server{
+		Addr:    ":" + options.Port,
+		Handler: mux,
+	}
+
+	return h
+}
@@ -0,0 +1,73 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gotway/gotway/pkg/log"
+)
+
+func newTestHealth() *Health {
+	return New(
+		Options{Port: "0", Timeout: time.Second},
+		log.NewLogger(log.Fields{}, "test", "error", io.Discard),
+	)
+}
+
+func get(t *testing.T, h *Health, path string) (int, report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var res report
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("error decoding report: %v", err)
+	}
+	return rec.Code, res
+}
+
+func TestHealthz(t *testing.T) {
+	h := newTestHealth()
+	h.AddReadinessCheck("lineq", func(ctx context.Context) (string, error) {
+		return "", errors.New("lineq unreachable")
+	})
+
+	code, res := get(t, h, HealthzPath)
+	if code != http.StatusOK || res.Status != statusOK {
+		t.Errorf("expected healthz to pass regardless of readiness, got %d %+v", code, res)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	h := newTestHealth()
+	h.AddReadinessCheck("leader", func(ctx context.Context) (string, error) {
+		return "leading", nil
+	})
+
+	code, res := get(t, h, ReadyzPath)
+	if code != http.StatusOK || res.Status != statusOK {
+		t.Fatalf("expected readyz to pass, got %d %+v", code, res)
+	}
+	if got := res.Checks["leader"]; got.Status != statusOK || got.Message != "leading" {
+		t.Errorf("unexpected leader check %+v", got)
+	}
+
+	h.AddReadinessCheck("informers", func(ctx context.Context) (string, error) {
+		return "", errors.New("informer caches not synced")
+	})
+	code, res = get(t, h, ReadyzPath)
+	if code != http.StatusServiceUnavailable || res.Status != statusError {
+		t.Fatalf("expected readyz to fail, got %d %+v", code, res)
+	}
+	if got := res.Checks["informers"]; got.Status != statusError || got.Message != "informer caches not synced" {
+		t.Errorf("unexpected informers check %+v", got)
+	}
+	if got := res.Checks["leader"]; got.Status != statusOK {
+		t.Errorf("expected passing checks to be reported, got %+v", got)
+	}
+}
@@ -0,0 +1,113 @@
+package informer
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Namespaced fans a shared informer out over a set of namespaces, informer
+// factories only scope to a single namespace or to the whole cluster.
+type Namespaced struct {
+	informers map[string]cache.SharedIndexInformer
+}
+
+// NewNamespaced creates one informer per namespace with newInformer. No
+// namespaces, or metav1.NamespaceAll, watch the whole cluster.
+func NewNamespaced(
+	namespaces []string,
+	newInformer func(namespace string) cache.SharedIndexInformer,
+) *Namespaced {
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	informers := make(map[string]cache.SharedIndexInformer, len(namespaces))
+	for _, ns := range namespaces {
+		if ns == metav1.NamespaceAll {
+			informers = map[string]cache.SharedIndexInformer{
+				metav1.NamespaceAll: newInformer(metav1.NamespaceAll),
+			}
+			break
+		}
+		informers[ns] = newInformer(ns)
+	}
+	return &Namespaced{informers: informers}
+}
+
+func (n *Namespaced) Run(stopCh <-chan struct{}) {
+	for _, i := range n.informers {
+		go i.Run(stopCh)
+	}
+}
+
+func (n *Namespaced) HasSynced() bool {
+	for _, i := range n.informers {
+		if !i.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *Namespaced) AddEventHandler(handler cache.ResourceEventHandler) error {
+	for ns, i := range n.informers {
+		if _, err := i.AddEventHandler(handler); err != nil {
+			return fmt.Errorf("error adding event handler in namespace '%s' %v", ns, err)
+		}
+	}
+	return nil
+}
+
+func (n *Namespaced) AddIndexers(indexers cache.Indexers) error {
+	for ns, i := range n.informers {
+		if err := i.AddIndexers(indexers); err != nil {
+			return fmt.Errorf("error adding indexers in namespace '%s' %v", ns, err)
+		}
+	}
+	return nil
+}
+
+// Indexer returns the indexer holding objects of namespace, or nil when the
+// namespace is not watched.
+func (n *Namespaced) Indexer(namespace string) cache.Indexer {
+	if i, ok := n.informers[metav1.NamespaceAll]; ok {
+		return i.GetIndexer()
+	}
+	if i, ok := n.informers[namespace]; ok {
+		return i.GetIndexer()
+	}
+	return nil
+}
+
+func (n *Namespaced) GetByKey(key string) (interface{}, bool, error) {
+	namespace, _, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+	indexer := n.Indexer(namespace)
+	if indexer == nil {
+		return nil, false, nil
+	}
+	return indexer.GetByKey(key)
+}
+
+func (n *Namespaced) List() []interface{} {
+	var objs []interface{}
+	for _, i := range n.informers {
+		objs = append(objs, i.GetIndexer().List()...)
+	}
+	return objs
+}
+
+func (n *Namespaced) ByIndex(indexName, indexedValue string) ([]interface{}, error) {
+	var objs []interface{}
+	for _, i := range n.informers {
+		found, err := i.GetIndexer().ByIndex(indexName, indexedValue)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, found...)
+	}
+	return objs, nil
+}
@@ -0,0 +1,80 @@
+package informer
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newService(name, namespace string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+	}
+}
+
+func newServiceInformer(t *testing.T, namespaces []string) *Namespaced {
+	t.Helper()
+	clientSet := fake.NewSimpleClientset(
+		newService("shop", "store"),
+		newService("blog", "news"),
+		newService("api", "other"),
+	)
+	n := NewNamespaced(namespaces, func(ns string) cache.SharedIndexInformer {
+		factory := informers.NewSharedInformerFactoryWithOptions(clientSet, time.Minute, informers.WithNamespace(ns))
+		return factory.Core().V1().Services().Informer()
+	})
+
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	n.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, n.HasSynced) {
+		t.Fatal("error waiting for informers to sync")
+	}
+	return n
+}
+
+func TestNamespaced(t *testing.T) {
+	tests := []struct {
+		name       string
+		namespaces []string
+		informers  int
+		listed     int
+	}{
+		{name: "all namespaces", namespaces: nil, informers: 1, listed: 3},
+		{name: "namespace all wins", namespaces: []string{"store", metav1.NamespaceAll}, informers: 1, listed: 3},
+		{name: "single namespace", namespaces: []string{"store"}, informers: 1, listed: 1},
+		{name: "multiple namespaces", namespaces: []string{"store", "news"}, informers: 2, listed: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newServiceInformer(t, tt.namespaces)
+			if len(n.informers) != tt.informers {
+				t.Errorf("expected %d informers, got %d", tt.informers, len(n.informers))
+			}
+			if got := len(n.List()); got != tt.listed {
+				t.Errorf("expected %d services, got %d", tt.listed, got)
+			}
+		})
+	}
+}
+
+func TestNamespacedGetByKey(t *testing.T) {
+	n := newServiceInformer(t, []string{"store", "news"})
+
+	for _, key := range []string{"store/shop", "news/blog"} {
+		if _, exists, err := n.GetByKey(key); err != nil || !exists {
+			t.Errorf("expected '%s' to exist, got exists=%v err=%v", key, exists, err)
+		}
+	}
+	if _, exists, err := n.GetByKey("other/api"); err != nil || exists {
+		t.Errorf("expected unwatched 'other/api' to be missing, got exists=%v err=%v", exists, err)
+	}
+	if n.Indexer("other") != nil {
+		t.Error("expected no indexer for unwatched namespace")
+	}
+}
@@ -0,0 +1,149 @@
+package lineq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gotway/gotway/pkg/log"
+)
+
+type Client interface {
+	CreateRoom(ctx context.Context, room Room) error
+	UpdateRoom(ctx context.Context, room Room) error
+	DeleteRoom(ctx context.Context, name string) error
+	GetRoom(ctx context.Context, name string) (RoomStats, error)
+	GetConfig(ctx context.Context) (Config, error)
+}
+
+type Options struct {
+	Addr    string
+	Port    int
+	Timeout time.Duration
+}
+
+type client struct {
+	baseURL    string
+	httpClient *http.Client
+	timeout    time.Duration
+	logger     log.Logger
+}
+
+func (c *client) CreateRoom(ctx context.Context, room Room) error {
+	var res response
+	return c.post(ctx, "create", room, &res)
+}
+
+func (c *client) UpdateRoom(ctx context.Context, room Room) error {
+	var res response
+	return c.post(ctx, "update", room, &res)
+}
+
+func (c *client) DeleteRoom(ctx context.Context, name string) error {
+	var res response
+	return c.post(ctx, "delete", Room{Name: name}, &res)
+}
+
+func (c *client) GetRoom(ctx context.Context, name string) (RoomStats, error) {
+	var res statsResponse
+	query := url.Values{"name": []string{name}}
+	if err := c.get(ctx, "getStats", query, &res); err != nil {
+		return RoomStats{}, err
+	}
+	return res.RoomStats, nil
+}
+
+func (c *client) GetConfig(ctx context.Context) (Config, error) {
+	var res configResponse
+	if err := c.get(ctx, "getConfig", nil, &res); err != nil {
+		return Config{}, err
+	}
+	return res.Config, nil
+}
+
+func (c *client) post(ctx context.Context, endpoint string, body interface{}, res statusResponse) error {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error encoding JSON: %v", err)
+	}
+	return c.do(ctx, http.MethodPost, endpoint, nil, bytes.NewReader(jsonData), res)
+}
+
+func (c *client) get(ctx context.Context, endpoint string, query url.Values, res statusResponse) error {
+	return c.do(ctx, http.MethodGet, endpoint, query, nil, res)
+}
+
+func (c *client) do(
+	ctx context.Context,
+	method, endpoint string,
+	query url.Values,
+	body io.Reader,
+	res statusResponse,
+) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	code := codeRequestFailed
+	defer func() {
+		requestDuration.WithLabelValues(endpoint, code).Observe(time.Since(start).Seconds())
+		if err != nil {
+			requestErrors.WithLabelValues(endpoint, code).Inc()
+		}
+	}()
+
+	reqURL := fmt.Sprintf("%s/%s", c.baseURL, endpoint)
+	if len(query) > 0 {
+		reqURL = reqURL + "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return &RequestError{Endpoint: endpoint, Err: err}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := c.httpClient.Do(req)
+	if err != nil {
+		return &RequestError{Endpoint: endpoint, Err: err}
+	}
+	defer response.Body.Close()
+	code = strconv.Itoa(response.StatusCode)
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return &StatusError{
+			Endpoint:   endpoint,
+			StatusCode: response.StatusCode,
+			Message:    string(bytes.TrimSpace(message)),
+		}
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(res); err != nil {
+		return &RequestError{Endpoint: endpoint, Err: fmt.Errorf("error decoding JSON response: %v", err)}
+	}
+	if status, message := res.status(); status == statusError {
+		return &StatusError{Endpoint: endpoint, StatusCode: response.StatusCode, Message: message}
+	}
+
+	c.logger.Debugf("lineq %s %s: '%s'", method, endpoint, response.Status)
+
+	return nil
+}
+
+func New(options Options, logger log.Logger) Client {
+	return &client{
+		baseURL:    fmt.Sprintf("http://%s:%d", options.Addr, options.Port),
+		httpClient: &http.Client{},
+		timeout:    options.Timeout,
+		logger:     logger,
+	}
+}
@@ -0,0 +1,118 @@
+package lineq_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gotway/gotway/pkg/log"
+
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq"
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq/lineqtest"
+)
+
+func newTestClient(t *testing.T) (lineq.Client, *lineqtest.Server) {
+	t.Helper()
+	server := lineqtest.NewServer()
+	t.Cleanup(server.Close)
+	logger := log.NewLogger(log.Fields{}, "test", "error", io.Discard)
+	return lineq.New(server.Options(), logger), server
+}
+
+func TestClientRoomLifecycle(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+	room := lineq.Room{Name: "example_com_", Path: "/", ActiveUsers: 10, Host: "example.com"}
+
+	if err := client.CreateRoom(ctx, room); err != nil {
+		t.Fatalf("unexpected error creating room: %v", err)
+	}
+	if err := client.CreateRoom(ctx, room); !lineq.IsAlreadyExists(err) {
+		t.Errorf("expected already exists error, got %v", err)
+	}
+
+	room.ActiveUsers = 30
+	if err := client.UpdateRoom(ctx, room); err != nil {
+		t.Fatalf("unexpected error updating room: %v", err)
+	}
+	if got := server.Rooms()[room.Name]; !reflect.DeepEqual(got, room) {
+		t.Errorf("expected room %+v, got %+v", room, got)
+	}
+
+	server.SetStats(room.Name, lineq.RoomStats{ActiveUsers: 30, WaitingUsers: 7})
+	stats, err := client.GetRoom(ctx, room.Name)
+	if err != nil {
+		t.Fatalf("unexpected error getting room: %v", err)
+	}
+	if stats.ActiveUsers != 30 || stats.WaitingUsers != 7 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	if err := client.DeleteRoom(ctx, room.Name); err != nil {
+		t.Fatalf("unexpected error deleting room: %v", err)
+	}
+	if err := client.DeleteRoom(ctx, room.Name); !lineq.IsNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestClientGetConfig(t *testing.T) {
+	client, server := newTestClient(t)
+	want := lineq.Config{RoomTableName: "rooms", UserTableName: "users", SessionDuration: 15}
+	server.SetConfig(want)
+
+	got, err := client.GetConfig(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("expected config %+v, got %+v", want, got)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	server.FailNext("getConfig", http.StatusBadGateway)
+	_, err := client.GetConfig(ctx)
+	var statusErr *lineq.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected status error with code %d, got %v", http.StatusBadGateway, err)
+	}
+
+	server.FailNext("create", http.StatusOK)
+	err = client.CreateRoom(ctx, lineq.Room{Name: "example_com_", Path: "/", ActiveUsers: 10, Host: "example.com"})
+	if !errors.As(err, &statusErr) || statusErr.Message != http.StatusText(http.StatusOK) {
+		t.Errorf("expected status error for an error payload, got %v", err)
+	}
+
+	server.SetLatency(2 * time.Second)
+	_, err = client.GetConfig(ctx)
+	var requestErr *lineq.RequestError
+	if !errors.As(err, &requestErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		config lineq.Config
+		valid  bool
+	}{
+		{config: lineq.Config{RoomTableName: "lineq_room", UserTableName: "lineq_user", SessionDuration: 5}, valid: true},
+		{config: lineq.Config{UserTableName: "lineq_user", SessionDuration: 5}},
+		{config: lineq.Config{RoomTableName: "lineq_room", SessionDuration: 5}},
+		{config: lineq.Config{RoomTableName: "lineq_room", UserTableName: "lineq_user"}},
+		{config: lineq.Config{RoomTableName: "lineq_room", UserTableName: "lineq_user", SessionDuration: -1}},
+	}
+	for _, tt := range tests {
+		if err := tt.config.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%+v) = %v, expected valid %v", tt.config, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package lineq
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type StatusError struct {
+	Endpoint   string
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("lineq %s returned status %d", e.Endpoint, e.StatusCode)
+	}
+	return fmt.Sprintf("lineq %s returned status %d: %s", e.Endpoint, e.StatusCode, e.Message)
+}
+
+type RequestError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("lineq %s request failed: %v", e.Endpoint, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+func IsNotFound(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+func IsAlreadyExists(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusConflict
+}
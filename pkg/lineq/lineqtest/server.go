@@ -0,0 +1,270 @@
+package lineqtest
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hamedetemaad/lineq-operator/pkg/lineq"
+)
+
+type Call struct {
+	Method   string
+	Endpoint string
+	Room     lineq.Room
+}
+
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	rooms    map[string]lineq.Room
+	stats    map[string]lineq.RoomStats
+	calls    []Call
+	failures map[string][]int
+	latency  time.Duration
+	config   lineq.Config
+}
+
+func NewServer() *Server {
+	s := &Server{
+		rooms:    make(map[string]lineq.Room),
+		stats:    make(map[string]lineq.RoomStats),
+		failures: make(map[string][]int),
+		config: lineq.Config{
+			RoomTableName:   "lineq_room",
+			UserTableName:   "lineq_user",
+			SessionDuration: 5,
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/create", s.handleCreate)
+	mux.HandleFunc("/update", s.handleUpdate)
+	mux.HandleFunc("/delete", s.handleDelete)
+	mux.HandleFunc("/getStats", s.handleGetStats)
+	mux.HandleFunc("/getConfig", s.handleGetConfig)
+	s.Server = httptest.NewServer(s.intercept(mux))
+
+	return s
+}
+
+func (s *Server) Options() lineq.Options {
+	host, port, _ := net.SplitHostPort(s.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return lineq.Options{
+		Addr:    host,
+		Port:    p,
+		Timeout: time.Second,
+	}
+}
+
+func (s *Server) Rooms() map[string]lineq.Room {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rooms := make(map[string]lineq.Room, len(s.rooms))
+	for k, v := range s.rooms {
+		rooms[k] = v
+	}
+	return rooms
+}
+
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+func (s *Server) AddRoom(room lineq.Room) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rooms[room.Name] = room
+}
+
+func (s *Server) SetStats(name string, stats lineq.RoomStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats[name] = stats
+}
+
+func (s *Server) SetConfig(config lineq.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
+}
+
+// FailNext makes the next requests to endpoint respond with the given status
+// codes, one per request, before it goes back to normal behaviour.
+func (s *Server) FailNext(endpoint string, statusCodes ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[endpoint] = append(s.failures[endpoint], statusCodes...)
+}
+
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = latency
+}
+
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		endpoint := r.URL.Path[1:]
+
+		s.mu.Lock()
+		latency := s.latency
+		var statusCode int
+		if failures := s.failures[endpoint]; len(failures) > 0 {
+			statusCode = failures[0]
+			s.failures[endpoint] = failures[1:]
+		}
+		s.mu.Unlock()
+
+		if latency > 0 {
+			select {
+			case <-time.After(latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if statusCode != 0 {
+			s.record(r.Method, endpoint, lineq.Room{})
+			writeJSON(w, statusCode, map[string]string{
+				"status":  "error",
+				"message": http.StatusText(statusCode),
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	room, ok := s.decodeRoom(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	_, exists := s.rooms[room.Name]
+	if !exists {
+		s.rooms[room.Name] = room
+	}
+	s.mu.Unlock()
+
+	if exists {
+		writeJSON(w, http.StatusConflict, map[string]string{"status": "error", "message": "room already exists"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "message": "room created"})
+}
+
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	room, ok := s.decodeRoom(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	_, exists := s.rooms[room.Name]
+	if exists {
+		s.rooms[room.Name] = room
+	}
+	s.mu.Unlock()
+
+	if !exists {
+		writeJSON(w, http.StatusNotFound, map[string]string{"status": "error", "message": "room not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "message": "room updated"})
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	room, ok := s.decodeRoom(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	_, exists := s.rooms[room.Name]
+	delete(s.rooms, room.Name)
+	delete(s.stats, room.Name)
+	s.mu.Unlock()
+
+	if !exists {
+		writeJSON(w, http.StatusNotFound, map[string]string{"status": "error", "message": "room not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "message": "room deleted"})
+}
+
+func (s *Server) handleGetStats(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	s.record(r.Method, "getStats", lineq.Room{Name: name})
+
+	s.mu.Lock()
+	_, exists := s.rooms[name]
+	stats := s.stats[name]
+	s.mu.Unlock()
+
+	if !exists {
+		writeJSON(w, http.StatusNotFound, map[string]string{"status": "error", "message": "room not found"})
+		return
+	}
+	res := map[string]interface{}{
+		"status":       "ok",
+		"activeUsers":  stats.ActiveUsers,
+		"waitingUsers": stats.WaitingUsers,
+	}
+	if stats.AdmittedUsers != nil {
+		res["admittedUsers"] = *stats.AdmittedUsers
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	s.record(r.Method, "getConfig", lineq.Room{})
+
+	s.mu.Lock()
+	config := s.config
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":                 "ok",
+		"lineq_room_table":       config.RoomTableName,
+		"lineq_user_table":       config.UserTableName,
+		"lineq_session_duration": config.SessionDuration,
+	})
+}
+
+func (s *Server) decodeRoom(w http.ResponseWriter, r *http.Request) (lineq.Room, bool) {
+	endpoint := r.URL.Path[1:]
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"status": "error"})
+		return lineq.Room{}, false
+	}
+	var room lineq.Room
+	if err := json.NewDecoder(r.Body).Decode(&room); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"status": "error", "message": err.Error()})
+		return lineq.Room{}, false
+	}
+	s.record(r.Method, endpoint, room)
+	return room, true
+}
+
+func (s *Server) record(method, endpoint string, room lineq.Room) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, Call{
+		Method:   method,
+		Endpoint: endpoint,
+		Room:     room,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,29 @@
+package lineq
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// codeRequestFailed labels calls that never got an HTTP response.
+const codeRequestFailed = "error"
+
+var (
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "lineq_operator",
+		Subsystem: "lineq",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of LineQ HTTP requests, by endpoint and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "code"})
+
+	requestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "lineq_operator",
+		Subsystem: "lineq",
+		Name:      "request_errors_total",
+		Help:      "Failed LineQ HTTP requests, by endpoint and status code.",
+	}, []string{"endpoint", "code"})
+)
+
+func init() {
+	prometheus.MustRegister(requestDuration, requestErrors)
+}
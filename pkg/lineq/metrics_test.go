@@ -0,0 +1,56 @@
+package lineq
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gotway/gotway/pkg/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestClientMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/getConfig" {
+			rw.Write([]byte(`{"status": "ok"}`))
+			return
+		}
+		http.Error(rw, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNum, _ := strconv.Atoi(port)
+	client := New(
+		Options{Addr: host, Port: portNum, Timeout: time.Second},
+		log.NewLogger(log.Fields{}, "test", "error", io.Discard),
+	)
+	ctx := context.Background()
+
+	errorsBefore := testutil.ToFloat64(requestErrors.WithLabelValues("create", "503"))
+	configErrorsBefore := testutil.ToFloat64(requestErrors.WithLabelValues("getConfig", "200"))
+	if err := client.CreateRoom(ctx, Room{Name: "shop"}); err == nil {
+		t.Fatal("expected error creating room")
+	}
+	if _, err := client.GetConfig(ctx); err != nil {
+		t.Fatalf("unexpected error getting config: %v", err)
+	}
+
+	if got := testutil.ToFloat64(requestErrors.WithLabelValues("create", "503")) - errorsBefore; got != 1 {
+		t.Errorf("expected 1 create error, got %v", got)
+	}
+	if got := testutil.ToFloat64(requestErrors.WithLabelValues("getConfig", "200")) - configErrorsBefore; got != 0 {
+		t.Errorf("expected no getConfig errors, got %v", got)
+	}
+	if got := testutil.CollectAndCount(requestDuration); got < 2 {
+		t.Errorf("expected request latencies for both endpoints, got %d series", got)
+	}
+}
@@ -0,0 +1,70 @@
+package lineq
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Room struct {
+	Name        string   `json:"name"`
+	Path        string   `json:"path"`
+	Paths       []string `json:"paths,omitempty"`
+	ActiveUsers int      `json:"activeUsers"`
+	Host        string   `json:"host"`
+}
+
+type RoomStats struct {
+	ActiveUsers  int `json:"activeUsers"`
+	WaitingUsers int `json:"waitingUsers"`
+	// AdmittedUsers counts the users admitted since the room was created,
+	// nil when LineQ doesn't report it.
+	AdmittedUsers *int64 `json:"admittedUsers,omitempty"`
+}
+
+type Config struct {
+	RoomTableName   string `json:"lineq_room_table"`
+	UserTableName   string `json:"lineq_user_table"`
+	SessionDuration int    `json:"lineq_session_duration"`
+}
+
+// Validate rejects configs HAProxy can't use, like the empty values LineQ
+// reports before it is initialized.
+func (c Config) Validate() error {
+	if c.RoomTableName == "" {
+		return errors.New("empty room table name")
+	}
+	if c.UserTableName == "" {
+		return errors.New("empty user table name")
+	}
+	if c.SessionDuration <= 0 {
+		return fmt.Errorf("invalid session duration %d", c.SessionDuration)
+	}
+	return nil
+}
+
+const statusError = "error"
+
+// statusResponse is implemented by every LineQ payload, LineQ may report a
+// failure in the status of a 2xx response.
+type statusResponse interface {
+	status() (string, string)
+}
+
+type response struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+func (r response) status() (string, string) {
+	return r.Status, r.Message
+}
+
+type statsResponse struct {
+	response
+	RoomStats
+}
+
+type configResponse struct {
+	response
+	Config
+}
@@ -14,10 +14,18 @@ import (
 func ForKind(kind schema.GroupVersionKind) interface{} {
 	switch kind {
 	// Group=lineq.io, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithKind("BackendPort"):
+		return &waitingroomv1alpha1.BackendPortApplyConfiguration{}
+	case v1alpha1.SchemeGroupVersion.WithKind("BackendRef"):
+		return &waitingroomv1alpha1.BackendRefApplyConfiguration{}
 	case v1alpha1.SchemeGroupVersion.WithKind("WaitingRoom"):
 		return &waitingroomv1alpha1.WaitingRoomApplyConfiguration{}
+	case v1alpha1.SchemeGroupVersion.WithKind("WaitingRoomPath"):
+		return &waitingroomv1alpha1.WaitingRoomPathApplyConfiguration{}
 	case v1alpha1.SchemeGroupVersion.WithKind("WaitingRoomSpec"):
 		return &waitingroomv1alpha1.WaitingRoomSpecApplyConfiguration{}
+	case v1alpha1.SchemeGroupVersion.WithKind("WaitingRoomStatus"):
+		return &waitingroomv1alpha1.WaitingRoomStatusApplyConfiguration{}
 
 	}
 	return nil
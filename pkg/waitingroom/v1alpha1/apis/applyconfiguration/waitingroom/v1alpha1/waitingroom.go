@@ -14,7 +14,8 @@ import (
 type WaitingRoomApplyConfiguration struct {
 	v1.TypeMetaApplyConfiguration    `json:",inline"`
 	*v1.ObjectMetaApplyConfiguration `json:"metadata,omitempty"`
-	Spec                             *WaitingRoomSpecApplyConfiguration `json:"spec,omitempty"`
+	Spec                             *WaitingRoomSpecApplyConfiguration   `json:"spec,omitempty"`
+	Status                           *WaitingRoomStatusApplyConfiguration `json:"status,omitempty"`
 }
 
 // WaitingRoom constructs an declarative configuration of the WaitingRoom type for use with
@@ -193,3 +194,11 @@ func (b *WaitingRoomApplyConfiguration) WithSpec(value *WaitingRoomSpecApplyConf
 	b.Spec = value
 	return b
 }
+
+// WithStatus sets the Status field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Status field is set to the value of the last call.
+func (b *WaitingRoomApplyConfiguration) WithStatus(value *WaitingRoomStatusApplyConfiguration) *WaitingRoomApplyConfiguration {
+	b.Status = value
+	return b
+}
@@ -6,12 +6,15 @@ package v1alpha1
 // WaitingRoomSpecApplyConfiguration represents an declarative configuration of the WaitingRoomSpec type for use
 // with apply.
 type WaitingRoomSpecApplyConfiguration struct {
-	Path           *string `json:"path,omitempty"`
-	ActiveUsers    *int    `json:"activeUsers,omitempty"`
-	Schema         *string `json:"schema,omitempty"`
-	Host           *string `json:"host,omitempty"`
-	BackendSvcAddr *string `json:"backendSvcAddr,omitempty"`
-	BackendSvcPort *int    `json:"backendSvcPort,omitempty"`
+	Path           *string                             `json:"path,omitempty"`
+	Paths          []WaitingRoomPathApplyConfiguration `json:"paths,omitempty"`
+	ActiveUsers    *int                                `json:"activeUsers,omitempty"`
+	Schema         *string                             `json:"schema,omitempty"`
+	TLSSecretName  *string                             `json:"tlsSecretName,omitempty"`
+	Host           *string                             `json:"host,omitempty"`
+	BackendRef     *BackendRefApplyConfiguration       `json:"backendRef,omitempty"`
+	BackendSvcAddr *string                             `json:"backendSvcAddr,omitempty"`
+	BackendSvcPort *int                                `json:"backendSvcPort,omitempty"`
 }
 
 // WaitingRoomSpecApplyConfiguration constructs an declarative configuration of the WaitingRoomSpec type for use with
@@ -28,6 +31,19 @@ func (b *WaitingRoomSpecApplyConfiguration) WithPath(value string) *WaitingRoomS
 	return b
 }
 
+// WithPaths adds the given value to the Paths field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the Paths field.
+func (b *WaitingRoomSpecApplyConfiguration) WithPaths(values ...*WaitingRoomPathApplyConfiguration) *WaitingRoomSpecApplyConfiguration {
+	for i := range values {
+		if values[i] == nil {
+			panic("nil value passed to WithPaths")
+		}
+		b.Paths = append(b.Paths, *values[i])
+	}
+	return b
+}
+
 // WithActiveUsers sets the ActiveUsers field in the declarative configuration to the given value
 // and returns the receiver, so that objects can be built by chaining "With" function invocations.
 // If called multiple times, the ActiveUsers field is set to the value of the last call.
@@ -44,6 +60,14 @@ func (b *WaitingRoomSpecApplyConfiguration) WithSchema(value string) *WaitingRoo
 	return b
 }
 
+// WithTLSSecretName sets the TLSSecretName field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the TLSSecretName field is set to the value of the last call.
+func (b *WaitingRoomSpecApplyConfiguration) WithTLSSecretName(value string) *WaitingRoomSpecApplyConfiguration {
+	b.TLSSecretName = &value
+	return b
+}
+
 // WithHost sets the Host field in the declarative configuration to the given value
 // and returns the receiver, so that objects can be built by chaining "With" function invocations.
 // If called multiple times, the Host field is set to the value of the last call.
@@ -52,6 +76,14 @@ func (b *WaitingRoomSpecApplyConfiguration) WithHost(value string) *WaitingRoomS
 	return b
 }
 
+// WithBackendRef sets the BackendRef field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the BackendRef field is set to the value of the last call.
+func (b *WaitingRoomSpecApplyConfiguration) WithBackendRef(value *BackendRefApplyConfiguration) *WaitingRoomSpecApplyConfiguration {
+	b.BackendRef = value
+	return b
+}
+
 // WithBackendSvcAddr sets the BackendSvcAddr field in the declarative configuration to the given value
 // and returns the receiver, so that objects can be built by chaining "With" function invocations.
 // If called multiple times, the BackendSvcAddr field is set to the value of the last call.
@@ -89,6 +89,18 @@ func (c *FakeWaitingRooms) Update(ctx context.Context, waitingRoom *v1alpha1.Wai
 	return obj.(*v1alpha1.WaitingRoom), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeWaitingRooms) UpdateStatus(ctx context.Context, waitingRoom *v1alpha1.WaitingRoom, opts v1.UpdateOptions) (*v1alpha1.WaitingRoom, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(waitingroomsResource, "status", c.ns, waitingRoom), &v1alpha1.WaitingRoom{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.WaitingRoom), err
+}
+
 // Delete takes name of the waitingRoom and deletes it. Returns an error if one occurs.
 func (c *FakeWaitingRooms) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	_, err := c.Fake.
@@ -137,3 +149,26 @@ func (c *FakeWaitingRooms) Apply(ctx context.Context, waitingRoom *waitingroomv1
 	}
 	return obj.(*v1alpha1.WaitingRoom), err
 }
+
+// ApplyStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+func (c *FakeWaitingRooms) ApplyStatus(ctx context.Context, waitingRoom *waitingroomv1alpha1.WaitingRoomApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.WaitingRoom, err error) {
+	if waitingRoom == nil {
+		return nil, fmt.Errorf("waitingRoom provided to Apply must not be nil")
+	}
+	data, err := json.Marshal(waitingRoom)
+	if err != nil {
+		return nil, err
+	}
+	name := waitingRoom.Name
+	if name == nil {
+		return nil, fmt.Errorf("waitingRoom.Name must be provided to Apply")
+	}
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(waitingroomsResource, c.ns, *name, types.ApplyPatchType, data, "status"), &v1alpha1.WaitingRoom{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.WaitingRoom), err
+}
@@ -28,6 +28,7 @@ type WaitingRoomsGetter interface {
 type WaitingRoomInterface interface {
 	Create(ctx context.Context, waitingRoom *v1alpha1.WaitingRoom, opts v1.CreateOptions) (*v1alpha1.WaitingRoom, error)
 	Update(ctx context.Context, waitingRoom *v1alpha1.WaitingRoom, opts v1.UpdateOptions) (*v1alpha1.WaitingRoom, error)
+	UpdateStatus(ctx context.Context, waitingRoom *v1alpha1.WaitingRoom, opts v1.UpdateOptions) (*v1alpha1.WaitingRoom, error)
 	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
 	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.WaitingRoom, error)
@@ -35,6 +36,7 @@ type WaitingRoomInterface interface {
 	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
 	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.WaitingRoom, err error)
 	Apply(ctx context.Context, waitingRoom *waitingroomv1alpha1.WaitingRoomApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.WaitingRoom, err error)
+	ApplyStatus(ctx context.Context, waitingRoom *waitingroomv1alpha1.WaitingRoomApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.WaitingRoom, err error)
 	WaitingRoomExpansion
 }
 
@@ -124,6 +126,22 @@ func (c *waitingRooms) Update(ctx context.Context, waitingRoom *v1alpha1.Waiting
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *waitingRooms) UpdateStatus(ctx context.Context, waitingRoom *v1alpha1.WaitingRoom, opts v1.UpdateOptions) (result *v1alpha1.WaitingRoom, err error) {
+	result = &v1alpha1.WaitingRoom{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("waitingrooms").
+		Name(waitingRoom.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(waitingRoom).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the waitingRoom and deletes it. Returns an error if one occurs.
 func (c *waitingRooms) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	return c.client.Delete().
@@ -191,3 +209,33 @@ func (c *waitingRooms) Apply(ctx context.Context, waitingRoom *waitingroomv1alph
 		Into(result)
 	return
 }
+
+// ApplyStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+func (c *waitingRooms) ApplyStatus(ctx context.Context, waitingRoom *waitingroomv1alpha1.WaitingRoomApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.WaitingRoom, err error) {
+	if waitingRoom == nil {
+		return nil, fmt.Errorf("waitingRoom provided to Apply must not be nil")
+	}
+	patchOpts := opts.ToPatchOptions()
+	data, err := json.Marshal(waitingRoom)
+	if err != nil {
+		return nil, err
+	}
+
+	name := waitingRoom.Name
+	if name == nil {
+		return nil, fmt.Errorf("waitingRoom.Name must be provided to Apply")
+	}
+
+	result = &v1alpha1.WaitingRoom{}
+	err = c.client.Patch(types.ApplyPatchType).
+		Namespace(c.ns).
+		Resource("waitingrooms").
+		Name(*name).
+		SubResource("status").
+		VersionedParams(&patchOpts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
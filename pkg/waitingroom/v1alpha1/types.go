@@ -1,25 +1,115 @@
 package v1alpha1
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ConditionSpecValid         = "SpecValid"
+	ConditionBackendRegistered = "BackendRegistered"
+	ConditionBackendResolved   = "BackendResolved"
+	ConditionIngressReady      = "IngressReady"
+	ConditionReady             = "Ready"
+)
 
 // +genclient
-// +genclient:noStatus
 // +k8s:deepcopy-gen=true
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type WaitingRoom struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
 
-	Spec WaitingRoomSpec `json:"spec"`
+	Spec   WaitingRoomSpec   `json:"spec"`
+	Status WaitingRoomStatus `json:"status,omitempty"`
 }
 
+const (
+	SchemaHTTP  = "http"
+	SchemaHTTPS = "https"
+)
+
+type PathType string
+
+const (
+	PathTypeExact  PathType = "Exact"
+	PathTypePrefix PathType = "Prefix"
+)
+
 type WaitingRoomSpec struct {
-	Path           string `json:"path"`
-	ActiveUsers    int    `json:"activeUsers"`
-	Schema         string `json:"schema"`
-	Host           string `json:"host"`
-	BackendSvcAddr string `json:"backendSvcAddr"`
-	BackendSvcPort int    `json:"backendSvcPort"`
+	Path           string            `json:"path,omitempty"`
+	Paths          []WaitingRoomPath `json:"paths,omitempty"`
+	ActiveUsers    int               `json:"activeUsers"`
+	Schema         string            `json:"schema"`
+	TLSSecretName  string            `json:"tlsSecretName,omitempty"`
+	Host           string            `json:"host"`
+	BackendRef     *BackendRef       `json:"backendRef,omitempty"`
+	BackendSvcAddr string            `json:"backendSvcAddr,omitempty"`
+	BackendSvcPort int               `json:"backendSvcPort,omitempty"`
+}
+
+// BackendRef points to the Service receiving admitted users. Namespace
+// defaults to the namespace of the WaitingRoom.
+type BackendRef struct {
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace,omitempty"`
+	Port      BackendPort `json:"port,omitempty"`
+}
+
+// BackendPort selects a Service port by name or number. When both are
+// empty the Service must expose a single port.
+type BackendPort struct {
+	Name   string `json:"name,omitempty"`
+	Number int32  `json:"number,omitempty"`
+}
+
+type WaitingRoomPath struct {
+	Path     string   `json:"path"`
+	PathType PathType `json:"pathType,omitempty"`
+}
+
+// GetPaths returns the paths sharing the room, falling back to the single
+// Path field. Paths without a type are matched exactly.
+func (s WaitingRoomSpec) GetPaths() []WaitingRoomPath {
+	paths := s.Paths
+	if len(paths) == 0 {
+		paths = []WaitingRoomPath{{Path: s.Path}}
+	}
+	result := make([]WaitingRoomPath, len(paths))
+	for i, p := range paths {
+		if p.PathType == "" {
+			p.PathType = PathTypeExact
+		}
+		result[i] = p
+	}
+	return result
+}
+
+type WaitingRoomStatus struct {
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+	IngressName        string             `json:"ingressName,omitempty"`
+	RoomName           string             `json:"roomName,omitempty"`
+	ActiveUsers        int                `json:"activeUsers"`
+	WaitingUsers       int                `json:"waitingUsers"`
+	LastSyncTime       *metav1.Time       `json:"lastSyncTime,omitempty"`
+}
+
+// RoomName returns the name the room is registered with in LineQ, derived
+// from the host and the lowest of its paths, so reordering them keeps the name.
+func (s WaitingRoomSpec) RoomName() string {
+	paths := s.GetPaths()
+	lowest := paths[0].Path
+	for _, p := range paths[1:] {
+		if p.Path < lowest {
+			lowest = p.Path
+		}
+	}
+	domain := strings.Replace(s.Host, ".", "_", -1)
+	path := strings.Replace(lowest, "/", "_", -1)
+	return fmt.Sprintf("%s%s", domain, path)
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -0,0 +1,135 @@
+// Package validation checks WaitingRoom specs. The admission webhook rejects
+// invalid specs with it and the controller refuses to reconcile them, for
+// clusters running without the webhook.
+package validation
+
+import (
+	"regexp"
+	"strings"
+
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func ValidateWaitingRoom(wr *wrv1alpha1.WaitingRoom) field.ErrorList {
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+
+	if wr.Spec.ActiveUsers <= 0 {
+		errs = append(errs, field.Invalid(spec.Child("activeUsers"), wr.Spec.ActiveUsers, "must be greater than 0"))
+	}
+
+	if wr.Spec.Host == "" {
+		errs = append(errs, field.Required(spec.Child("host"), ""))
+	} else {
+		for _, msg := range validation.IsDNS1123Subdomain(wr.Spec.Host) {
+			errs = append(errs, field.Invalid(spec.Child("host"), wr.Spec.Host, msg))
+		}
+	}
+
+	switch wr.Spec.Schema {
+	case "", wrv1alpha1.SchemaHTTP, wrv1alpha1.SchemaHTTPS:
+	default:
+		errs = append(errs, field.NotSupported(
+			spec.Child("schema"),
+			wr.Spec.Schema,
+			[]string{wrv1alpha1.SchemaHTTP, wrv1alpha1.SchemaHTTPS},
+		))
+	}
+	if wr.Spec.Schema == wrv1alpha1.SchemaHTTPS && wr.Spec.TLSSecretName == "" {
+		errs = append(errs, field.Required(spec.Child("tlsSecretName"), "required when schema is https"))
+	}
+
+	if wr.Spec.Path != "" && len(wr.Spec.Paths) > 0 {
+		errs = append(errs, field.Forbidden(spec.Child("path"), "may not be set together with paths"))
+	}
+	if len(wr.Spec.Paths) == 0 {
+		errs = append(errs, validatePath(spec.Child("path"), wr.Spec.Path)...)
+	}
+	seen := make(map[string]bool)
+	for i, p := range wr.Spec.Paths {
+		idx := spec.Child("paths").Index(i)
+		errs = append(errs, validatePath(idx.Child("path"), p.Path)...)
+		switch p.PathType {
+		case "", wrv1alpha1.PathTypeExact, wrv1alpha1.PathTypePrefix:
+		default:
+			errs = append(errs, field.NotSupported(
+				idx.Child("pathType"),
+				p.PathType,
+				[]string{string(wrv1alpha1.PathTypeExact), string(wrv1alpha1.PathTypePrefix)},
+			))
+		}
+		if seen[p.Path] {
+			errs = append(errs, field.Duplicate(idx.Child("path"), p.Path))
+		}
+		seen[p.Path] = true
+	}
+
+	if wr.Spec.BackendRef != nil {
+		errs = append(errs, validateBackendRef(spec.Child("backendRef"), wr.Spec.BackendRef)...)
+		if wr.Spec.BackendSvcAddr != "" || wr.Spec.BackendSvcPort != 0 {
+			errs = append(errs, field.Forbidden(spec.Child("backendRef"), "may not be set together with backendSvcAddr or backendSvcPort"))
+		}
+		return errs
+	}
+	if wr.Spec.BackendSvcAddr == "" {
+		errs = append(errs, field.Required(spec.Child("backendSvcAddr"), "backendRef or backendSvcAddr is required"))
+	}
+	if wr.Spec.BackendSvcPort < 1 || wr.Spec.BackendSvcPort > 65535 {
+		errs = append(errs, field.Invalid(spec.Child("backendSvcPort"), wr.Spec.BackendSvcPort, "must be between 1 and 65535"))
+	}
+
+	return errs
+}
+
+func validateBackendRef(fldPath *field.Path, ref *wrv1alpha1.BackendRef) field.ErrorList {
+	var errs field.ErrorList
+
+	if ref.Name == "" {
+		errs = append(errs, field.Required(fldPath.Child("name"), ""))
+	} else {
+		for _, msg := range validation.IsDNS1035Label(ref.Name) {
+			errs = append(errs, field.Invalid(fldPath.Child("name"), ref.Name, msg))
+		}
+	}
+	if ref.Namespace != "" {
+		for _, msg := range validation.IsDNS1123Label(ref.Namespace) {
+			errs = append(errs, field.Invalid(fldPath.Child("namespace"), ref.Namespace, msg))
+		}
+	}
+
+	port := fldPath.Child("port")
+	if ref.Port.Name != "" && ref.Port.Number != 0 {
+		errs = append(errs, field.Forbidden(port, "name and number are mutually exclusive"))
+	}
+	if ref.Port.Number != 0 {
+		for _, msg := range validation.IsValidPortNum(int(ref.Port.Number)) {
+			errs = append(errs, field.Invalid(port.Child("number"), ref.Port.Number, msg))
+		}
+	}
+	if ref.Port.Name != "" {
+		for _, msg := range validation.IsValidPortName(ref.Port.Name) {
+			errs = append(errs, field.Invalid(port.Child("name"), ref.Port.Name, msg))
+		}
+	}
+
+	return errs
+}
+
+// pathPattern matches the paths that can be written unquoted in the HAProxy
+// frontend.
+var pathPattern = regexp.MustCompile(`^/[A-Za-z0-9/._~%@:+-]*$`)
+
+func validatePath(fldPath *field.Path, path string) field.ErrorList {
+	if path == "" {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+	if !strings.HasPrefix(path, "/") {
+		return field.ErrorList{field.Invalid(fldPath, path, "must start with '/'")}
+	}
+	if !pathPattern.MatchString(path) {
+		return field.ErrorList{field.Invalid(fldPath, path, "must only contain letters, digits and '/._~%@:+-'")}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package validation
+
+import (
+	"testing"
+
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newWaitingRoom(namespace, name string) *wrv1alpha1.WaitingRoom {
+	return &wrv1alpha1.WaitingRoom{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: wrv1alpha1.WaitingRoomSpec{
+			Path:           "/checkout",
+			ActiveUsers:    20,
+			Schema:         "http",
+			Host:           "example.com",
+			BackendSvcAddr: "shop",
+			BackendSvcPort: 80,
+		},
+	}
+}
+
+func TestValidateWaitingRoom(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(wr *wrv1alpha1.WaitingRoom)
+		errs   []string
+	}{
+		{
+			name:   "valid",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {},
+		},
+		{
+			name: "valid paths",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {
+				wr.Spec.Path = ""
+				wr.Spec.Paths = []wrv1alpha1.WaitingRoomPath{
+					{Path: "/cart"},
+					{Path: "/pay", PathType: wrv1alpha1.PathTypePrefix},
+				}
+			},
+		},
+		{
+			name:   "zero active users",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) { wr.Spec.ActiveUsers = 0 },
+			errs:   []string{"spec.activeUsers"},
+		},
+		{
+			name:   "negative active users",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) { wr.Spec.ActiveUsers = -5 },
+			errs:   []string{"spec.activeUsers"},
+		},
+		{
+			name:   "relative path",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) { wr.Spec.Path = "checkout" },
+			errs:   []string{"spec.path"},
+		},
+		{
+			name:   "path with spaces",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) { wr.Spec.Path = "/check out" },
+			errs:   []string{"spec.path"},
+		},
+		{
+			name:   "path with haproxy syntax",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) { wr.Spec.Path = "/checkout}" },
+			errs:   []string{"spec.path"},
+		},
+		{
+			name:   "empty path",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) { wr.Spec.Path = "" },
+			errs:   []string{"spec.path"},
+		},
+		{
+			name:   "port out of range",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) { wr.Spec.BackendSvcPort = 70000 },
+			errs:   []string{"spec.backendSvcPort"},
+		},
+		{
+			name:   "invalid host",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) { wr.Spec.Host = "Example_com" },
+			errs:   []string{"spec.host"},
+		},
+		{
+			name:   "invalid schema",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) { wr.Spec.Schema = "ftp" },
+			errs:   []string{"spec.schema"},
+		},
+		{
+			name: "https",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {
+				wr.Spec.Schema = wrv1alpha1.SchemaHTTPS
+				wr.Spec.TLSSecretName = "example-com-tls"
+			},
+		},
+		{
+			name:   "https without tls secret",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) { wr.Spec.Schema = wrv1alpha1.SchemaHTTPS },
+			errs:   []string{"spec.tlsSecretName"},
+		},
+		{
+			name: "path and paths",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {
+				wr.Spec.Paths = []wrv1alpha1.WaitingRoomPath{{Path: "/cart"}}
+			},
+			errs: []string{"spec.path"},
+		},
+		{
+			name: "backendRef",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {
+				wr.Spec.BackendSvcAddr = ""
+				wr.Spec.BackendSvcPort = 0
+				wr.Spec.BackendRef = &wrv1alpha1.BackendRef{
+					Name: "shop",
+					Port: wrv1alpha1.BackendPort{Name: "http"},
+				}
+			},
+		},
+		{
+			name: "backendRef with backendSvcAddr",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {
+				wr.Spec.BackendRef = &wrv1alpha1.BackendRef{Name: "shop"}
+			},
+			errs: []string{"spec.backendRef"},
+		},
+		{
+			name: "invalid backendRef",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {
+				wr.Spec.BackendSvcAddr = ""
+				wr.Spec.BackendSvcPort = 0
+				wr.Spec.BackendRef = &wrv1alpha1.BackendRef{
+					Name: "Shop",
+					Port: wrv1alpha1.BackendPort{Name: "http", Number: 80},
+				}
+			},
+			errs: []string{"spec.backendRef.name", "spec.backendRef.port"},
+		},
+		{
+			name: "missing backend",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {
+				wr.Spec.BackendSvcAddr = ""
+			},
+			errs: []string{"spec.backendSvcAddr"},
+		},
+		{
+			name: "invalid paths",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {
+				wr.Spec.Path = ""
+				wr.Spec.Paths = []wrv1alpha1.WaitingRoomPath{
+					{Path: "/cart"},
+					{Path: "/cart"},
+					{Path: "pay", PathType: "Regex"},
+				}
+			},
+			errs: []string{"spec.paths[1].path", "spec.paths[2].path", "spec.paths[2].pathType"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wr := newWaitingRoom("test", "shop")
+			tt.mutate(wr)
+			errs := ValidateWaitingRoom(wr)
+			if len(errs) != len(tt.errs) {
+				t.Fatalf("expected %d errors, got %v", len(tt.errs), errs)
+			}
+			for i, field := range tt.errs {
+				if errs[i].Field != field {
+					t.Errorf("expected error on %s, got %v", field, errs[i])
+				}
+			}
+		})
+	}
+}
@@ -5,5 +5,6 @@ const (
 
 	V1alpha1 = "v1alpha1"
 
-	WaitingRoomKind          string = "WaitingRoom"
+	WaitingRoomKind      string = "WaitingRoom"
+	WaitingRoomFinalizer string = GroupName + "/finalizer"
 )
@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+func (w *Webhook) mutate(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return allowed()
+	}
+
+	var wr wrv1alpha1.WaitingRoom
+	if err := json.Unmarshal(req.Object.Raw, &wr); err != nil {
+		return denied(http.StatusBadRequest, fmt.Sprintf("error decoding waiting room %v", err))
+	}
+	if wr.Namespace == "" {
+		wr.Namespace = req.Namespace
+	}
+
+	patch := defaultWaitingRoom(&wr, w.lookupService)
+	if len(patch) == 0 {
+		return allowed()
+	}
+
+	raw, err := json.Marshal(patch)
+	if err != nil {
+		return denied(http.StatusInternalServerError, fmt.Sprintf("error encoding patch %v", err))
+	}
+	w.logger.Debugf("defaulting waiting room '%s/%s': %s", wr.Namespace, wr.Name, raw)
+
+	patchType := admissionv1.PatchTypeJSONPatch
+	response := allowed()
+	response.Patch = raw
+	response.PatchType = &patchType
+	return response
+}
+
+type serviceLookup func(namespace, name string) (*corev1.Service, error)
+
+// defaultWaitingRoom returns the JSON patch filling in the fields users
+// usually leave out.
+func defaultWaitingRoom(wr *wrv1alpha1.WaitingRoom, lookup serviceLookup) []patchOperation {
+	var patch []patchOperation
+
+	if wr.Spec.Schema == "" {
+		patch = append(patch, patchOperation{
+			Op:    "add",
+			Path:  "/spec/schema",
+			Value: wrv1alpha1.SchemaHTTP,
+		})
+	}
+
+	if wr.Spec.Path == "" && len(wr.Spec.Paths) == 0 {
+		patch = append(patch, patchOperation{
+			Op:    "add",
+			Path:  "/spec/path",
+			Value: "/",
+		})
+	}
+
+	if wr.Spec.BackendSvcPort == 0 && wr.Spec.BackendSvcAddr != "" {
+		if name, ok := serviceRef(wr.Namespace, wr.Spec.BackendSvcAddr); ok {
+			svc, err := lookup(wr.Namespace, name)
+			if err == nil && len(svc.Spec.Ports) == 1 {
+				patch = append(patch, patchOperation{
+					Op:    "add",
+					Path:  "/spec/backendSvcPort",
+					Value: svc.Spec.Ports[0].Port,
+				})
+			}
+		}
+	}
+
+	return patch
+}
+
+// serviceRef resolves backendSvcAddr to the name of a Service in the
+// namespace of the room, either a bare name or a cluster DNS name like
+// 'shop.store.svc'. Ingress backends can't cross namespaces, so it reports
+// false for anything else.
+func serviceRef(namespace, addr string) (string, bool) {
+	parts := strings.Split(addr, ".")
+	if len(parts) == 1 {
+		return parts[0], true
+	}
+	if parts[1] != namespace || (len(parts) > 2 && parts[2] != "svc") {
+		return "", false
+	}
+	return parts[0], true
+}
+
+func (w *Webhook) lookupService(namespace, name string) (*corev1.Service, error) {
+	obj, exists, err := w.svcInformer.GetByKey(namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, apierrors.NewNotFound(corev1.Resource("services"), name)
+	}
+	svc, ok := obj.(*corev1.Service)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %v", obj)
+	}
+	return svc, nil
+}
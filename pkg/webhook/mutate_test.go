@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/gotway/gotway/pkg/log"
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newService(namespace, name string, ports ...int32) *corev1.Service {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+	}
+	for _, port := range ports {
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{Port: port})
+	}
+	return svc
+}
+
+func fakeLookup(services ...*corev1.Service) serviceLookup {
+	return func(namespace, name string) (*corev1.Service, error) {
+		for _, svc := range services {
+			if svc.Namespace == namespace && svc.Name == name {
+				return svc, nil
+			}
+		}
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "services"}, name)
+	}
+}
+
+func TestDefaultWaitingRoom(t *testing.T) {
+	services := fakeLookup(
+		newService("test", "shop", 8080),
+		newService("store", "cart", 9090),
+		newService("test", "multi", 80, 443),
+	)
+
+	tests := []struct {
+		name   string
+		mutate func(wr *wrv1alpha1.WaitingRoom)
+		patch  []patchOperation
+	}{
+		{
+			name:   "complete",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {},
+		},
+		{
+			name: "minimal",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {
+				wr.Spec.Schema = ""
+				wr.Spec.Path = ""
+				wr.Spec.BackendSvcPort = 0
+			},
+			patch: []patchOperation{
+				{Op: "add", Path: "/spec/schema", Value: "http"},
+				{Op: "add", Path: "/spec/path", Value: "/"},
+				{Op: "add", Path: "/spec/backendSvcPort", Value: int32(8080)},
+			},
+		},
+		{
+			name: "paths set",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {
+				wr.Spec.Path = ""
+				wr.Spec.Paths = []wrv1alpha1.WaitingRoomPath{{Path: "/cart"}}
+			},
+		},
+		{
+			name: "service dns name",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {
+				wr.Spec.BackendSvcAddr = "shop.test.svc.cluster.local"
+				wr.Spec.BackendSvcPort = 0
+			},
+			patch: []patchOperation{
+				{Op: "add", Path: "/spec/backendSvcPort", Value: int32(8080)},
+			},
+		},
+		{
+			name: "service in other namespace",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {
+				wr.Spec.BackendSvcAddr = "cart.store.svc.cluster.local"
+				wr.Spec.BackendSvcPort = 0
+			},
+		},
+		{
+			name: "external host",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {
+				wr.Spec.BackendSvcAddr = "shop.test.example.com"
+				wr.Spec.BackendSvcPort = 0
+			},
+		},
+		{
+			name: "service with several ports",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {
+				wr.Spec.BackendSvcAddr = "multi"
+				wr.Spec.BackendSvcPort = 0
+			},
+		},
+		{
+			name: "missing service",
+			mutate: func(wr *wrv1alpha1.WaitingRoom) {
+				wr.Spec.BackendSvcAddr = "missing"
+				wr.Spec.BackendSvcPort = 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wr := newWaitingRoom("test", "shop")
+			wr.Spec.BackendSvcAddr = "shop"
+			tt.mutate(wr)
+			patch := defaultWaitingRoom(wr, services)
+			if !reflect.DeepEqual(patch, tt.patch) {
+				t.Errorf("expected patch %+v, got %+v", tt.patch, patch)
+			}
+		})
+	}
+}
+
+func TestMutatePatchType(t *testing.T) {
+	w := &Webhook{logger: log.NewLogger(log.Fields{}, "test", "error", io.Discard)}
+	wr := newWaitingRoom("test", "shop")
+	wr.Spec.Schema = ""
+	raw, err := json.Marshal(wr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := w.mutate(&admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Namespace: "test",
+		Object:    runtime.RawExtension{Raw: raw},
+	})
+	if !res.Allowed {
+		t.Fatalf("expected request to be allowed, got %v", res.Result)
+	}
+	if res.PatchType == nil || *res.PatchType != admissionv1.PatchTypeJSONPatch {
+		t.Fatalf("expected JSONPatch, got %v", res.PatchType)
+	}
+	var patch []patchOperation
+	if err := json.Unmarshal(res.Patch, &patch); err != nil {
+		t.Fatal(err)
+	}
+	if len(patch) != 1 || patch[0].Path != "/spec/schema" {
+		t.Errorf("unexpected patch %s", res.Patch)
+	}
+}
@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"fmt"
+
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// validateConflicts rejects rooms claiming a host and path, or a LineQ room
+// name, that already belongs to another waiting room.
+func validateConflicts(wr *wrv1alpha1.WaitingRoom, others []*wrv1alpha1.WaitingRoom) field.ErrorList {
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+	roomName := wr.Spec.RoomName()
+
+	for _, other := range others {
+		if other.Namespace == wr.Namespace && other.Name == wr.Name {
+			continue
+		}
+		if other.DeletionTimestamp != nil {
+			continue
+		}
+		owner := fmt.Sprintf("%s/%s", other.Namespace, other.Name)
+
+		if other.Spec.RoomName() == roomName {
+			errs = append(errs, field.Invalid(
+				spec.Child("host"),
+				wr.Spec.Host,
+				fmt.Sprintf("lineq room '%s' is already used by waiting room %s", roomName, owner),
+			))
+			continue
+		}
+
+		if other.Spec.Host != wr.Spec.Host {
+			continue
+		}
+		otherPaths := make(map[string]bool)
+		for _, p := range other.Spec.GetPaths() {
+			otherPaths[p.Path] = true
+		}
+		for _, p := range wr.Spec.GetPaths() {
+			if otherPaths[p.Path] {
+				errs = append(errs, field.Invalid(
+					spec.Child("paths"),
+					p.Path,
+					fmt.Sprintf("host '%s' and path '%s' are already claimed by waiting room %s", wr.Spec.Host, p.Path, owner),
+				))
+			}
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,157 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gotway/gotway/pkg/log"
+	"github.com/hamedetemaad/lineq-operator/pkg/waitingroom"
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newWaitingRoom(namespace, name string) *wrv1alpha1.WaitingRoom {
+	return &wrv1alpha1.WaitingRoom{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: wrv1alpha1.WaitingRoomSpec{
+			Path:           "/checkout",
+			ActiveUsers:    20,
+			Schema:         "http",
+			Host:           "example.com",
+			BackendSvcAddr: "shop",
+			BackendSvcPort: 80,
+		},
+	}
+}
+
+func TestValidateConflicts(t *testing.T) {
+	existing := newWaitingRoom("test", "shop")
+	deleting := newWaitingRoom("other", "deleting")
+	deleting.Spec.Path = "/deleting"
+	now := metav1.NewTime(time.Now())
+	deleting.DeletionTimestamp = &now
+	others := []*wrv1alpha1.WaitingRoom{existing, deleting}
+
+	tests := []struct {
+		name     string
+		wr       *wrv1alpha1.WaitingRoom
+		conflict bool
+	}{
+		{
+			name: "same object",
+			wr:   newWaitingRoom("test", "shop"),
+		},
+		{
+			name:     "same host and path",
+			wr:       newWaitingRoom("other", "shop"),
+			conflict: true,
+		},
+		{
+			name: "same host and path in paths",
+			wr: func() *wrv1alpha1.WaitingRoom {
+				wr := newWaitingRoom("test", "checkout")
+				wr.Spec.Path = ""
+				wr.Spec.Paths = []wrv1alpha1.WaitingRoomPath{{Path: "/cart"}, {Path: "/checkout"}}
+				return wr
+			}(),
+			conflict: true,
+		},
+		{
+			name: "same room name",
+			wr: func() *wrv1alpha1.WaitingRoom {
+				wr := newWaitingRoom("test", "underscore")
+				wr.Spec.Host = "example_com"
+				return wr
+			}(),
+			conflict: true,
+		},
+		{
+			name: "different path",
+			wr: func() *wrv1alpha1.WaitingRoom {
+				wr := newWaitingRoom("test", "pay")
+				wr.Spec.Path = "/pay"
+				return wr
+			}(),
+		},
+		{
+			name: "deleting room",
+			wr: func() *wrv1alpha1.WaitingRoom {
+				wr := newWaitingRoom("test", "new")
+				wr.Spec.Path = "/deleting"
+				return wr
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateConflicts(tt.wr, others)
+			if tt.conflict && len(errs) == 0 {
+				t.Error("expected conflict")
+			}
+			if !tt.conflict && len(errs) > 0 {
+				t.Errorf("unexpected conflict %v", errs)
+			}
+			if tt.conflict && len(errs) > 0 && !strings.Contains(errs.ToAggregate().Error(), "test/shop") {
+				t.Errorf("expected conflict to name the owning room, got %v", errs)
+			}
+		})
+	}
+}
+
+func TestValidateSkipsUnchangedSpec(t *testing.T) {
+	w := &Webhook{logger: log.NewLogger(log.Fields{}, "test", "error", io.Discard)}
+	invalid := newWaitingRoom("test", "shop")
+	invalid.Spec.ActiveUsers = 0
+	invalid.Finalizers = []string{waitingroom.WaitingRoomFinalizer}
+
+	withoutFinalizer := invalid.DeepCopy()
+	withoutFinalizer.Finalizers = nil
+	deleting := withoutFinalizer.DeepCopy()
+	now := metav1.NewTime(time.Now())
+	deleting.DeletionTimestamp = &now
+	relabelled := withoutFinalizer.DeepCopy()
+	relabelled.Labels = map[string]string{"lineq.io/shard": "a"}
+	changed := withoutFinalizer.DeepCopy()
+	changed.Spec.Path = "/pay"
+
+	tests := []struct {
+		name    string
+		old     *wrv1alpha1.WaitingRoom
+		wr      *wrv1alpha1.WaitingRoom
+		allowed bool
+	}{
+		{name: "finalizer removal", old: invalid, wr: deleting, allowed: true},
+		{name: "metadata only", old: invalid, wr: relabelled, allowed: true},
+		{name: "spec change", old: invalid, wr: changed, allowed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := json.Marshal(tt.wr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			oldRaw, err := json.Marshal(tt.old)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := w.validate(&admissionv1.AdmissionRequest{
+				Operation: admissionv1.Update,
+				Namespace: "test",
+				Object:    runtime.RawExtension{Raw: raw},
+				OldObject: runtime.RawExtension{Raw: oldRaw},
+			})
+			if res.Allowed != tt.allowed {
+				t.Errorf("expected allowed %v, got %v", tt.allowed, res.Result)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gotway/gotway/pkg/log"
+
+	"github.com/hamedetemaad/lineq-operator/pkg/informer"
+	wrv1alpha1 "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1"
+	wrv1alpha1clientset "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1/apis/clientset/versioned"
+	wrinformers "github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1/apis/informers/externalversions"
+	"github.com/hamedetemaad/lineq-operator/pkg/waitingroom/v1alpha1/validation"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	ValidatePath = "/validate-waitingroom"
+	MutatePath   = "/mutate-waitingroom"
+)
+
+type Options struct {
+	Port     string
+	CertFile string
+	KeyFile  string
+}
+
+type Webhook struct {
+	options     Options
+	server      *http.Server
+	wrInformer  *informer.Namespaced
+	svcInformer *informer.Namespaced
+	logger      log.Logger
+}
+
+func (w *Webhook) Start(ctx context.Context) {
+	w.wrInformer.Run(ctx.Done())
+	w.svcInformer.Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), w.wrInformer.HasSynced, w.svcInformer.HasSynced) {
+		w.logger.Error("failed to wait for webhook informer cache to sync")
+		return
+	}
+
+	w.logger.Infof("webhook server listening in :%s", w.options.Port)
+	err := w.server.ListenAndServeTLS(w.options.CertFile, w.options.KeyFile)
+	if err != nil && err != http.ErrServerClosed {
+		w.logger.Error("error starting webhook server ", err)
+	}
+}
+
+func (w *Webhook) Stop() {
+	if err := w.server.Shutdown(context.Background()); err != nil {
+		w.logger.Error("error stopping webhook server ", err)
+		return
+	}
+	w.logger.Info("stopped webhook server")
+}
+
+func (w *Webhook) serveValidate(rw http.ResponseWriter, r *http.Request) {
+	w.serve(rw, r, w.validate)
+}
+
+func (w *Webhook) serveMutate(rw http.ResponseWriter, r *http.Request) {
+	w.serve(rw, r, w.mutate)
+}
+
+type admitFunc func(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse
+
+func (w *Webhook) serve(rw http.ResponseWriter, r *http.Request, admit admitFunc) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("error reading body %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(body, &review); err != nil {
+		http.Error(rw, fmt.Sprintf("error decoding admission review %v", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(rw, "admission review without request", http.StatusBadRequest)
+		return
+	}
+
+	response := admit(review.Request)
+	response.UID = review.Request.UID
+	review.Response = response
+	review.Request = nil
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(review); err != nil {
+		w.logger.Error("error encoding admission review ", err)
+	}
+}
+
+func (w *Webhook) validate(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return allowed()
+	}
+
+	var wr wrv1alpha1.WaitingRoom
+	if err := json.Unmarshal(req.Object.Raw, &wr); err != nil {
+		return denied(http.StatusBadRequest, fmt.Sprintf("error decoding waiting room %v", err))
+	}
+	if wr.Namespace == "" {
+		wr.Namespace = req.Namespace
+	}
+	// Rooms being deleted, and updates leaving the spec alone such as
+	// finalizer removals, must go through even if the room is invalid.
+	if wr.DeletionTimestamp != nil {
+		return allowed()
+	}
+	if req.Operation == admissionv1.Update {
+		var oldWr wrv1alpha1.WaitingRoom
+		if err := json.Unmarshal(req.OldObject.Raw, &oldWr); err != nil {
+			return denied(http.StatusBadRequest, fmt.Sprintf("error decoding old waiting room %v", err))
+		}
+		if reflect.DeepEqual(oldWr.Spec, wr.Spec) {
+			return allowed()
+		}
+	}
+
+	errs := validation.ValidateWaitingRoom(&wr)
+	if len(errs) == 0 {
+		errs = append(errs, validateConflicts(&wr, w.waitingRooms())...)
+	}
+	if len(errs) > 0 {
+		w.logger.Debugf("rejecting waiting room '%s/%s': %v", wr.Namespace, wr.Name, errs.ToAggregate())
+		return denied(http.StatusUnprocessableEntity, errs.ToAggregate().Error())
+	}
+	return allowed()
+}
+
+func (w *Webhook) waitingRooms() []*wrv1alpha1.WaitingRoom {
+	objs := w.wrInformer.List()
+	wrs := make([]*wrv1alpha1.WaitingRoom, 0, len(objs))
+	for _, obj := range objs {
+		if wr, ok := obj.(*wrv1alpha1.WaitingRoom); ok {
+			wrs = append(wrs, wr)
+		}
+	}
+	return wrs
+}
+
+func allowed() *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+func denied(code int32, message string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    code,
+			Message: message,
+		},
+	}
+}
+
+func New(
+	kubeClientSet kubernetes.Interface,
+	wrClientSet wrv1alpha1clientset.Interface,
+	namespaces []string,
+	options Options,
+	logger log.Logger,
+) *Webhook {
+	wrInformer := informer.NewNamespaced(namespaces, func(ns string) cache.SharedIndexInformer {
+		factory := wrinformers.NewSharedInformerFactoryWithOptions(
+			wrClientSet,
+			10*time.Second,
+			wrinformers.WithNamespace(ns),
+		)
+		return factory.Lineq().V1alpha1().WaitingRooms().Informer()
+	})
+	svcInformer := informer.NewNamespaced(namespaces, func(ns string) cache.SharedIndexInformer {
+		factory := informers.NewSharedInformerFactoryWithOptions(
+			kubeClientSet,
+			10*time.Second,
+			informers.WithNamespace(ns),
+		)
+		return factory.Core().V1().Services().Informer()
+	})
+
+	w := &Webhook{
+		options:     options,
+		wrInformer:  wrInformer,
+		svcInformer: svcInformer,
+		logger:      logger,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(ValidatePath, w.serveValidate)
+	mux.HandleFunc(MutatePath, w.serveMutate)
+	w.server = &http.Server{
+		Addr:    ":" + options.Port,
+		Handler: mux,
+	}
+
+	return w
+}